@@ -1,12 +1,8 @@
 package main
 
-import (
-	"github.com/julienschmidt/httprouter"
-)
-
 // API represents an API (yep)
 type API interface {
-	Route(router *httprouter.Router)
+	Route(router *instrumentedRouter)
 }
 
 type apiResult struct {
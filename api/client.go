@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/tjhorner/makerbot-rpc"
@@ -19,6 +22,76 @@ type apiResult struct {
 	Result json.RawMessage `json:"result"`
 }
 
+// PrinterConfig mirrors a printer entry in the makerbotd config file
+type PrinterConfig struct {
+	Name           string
+	Tags           []string
+	ConnectionType string
+	ID             string
+	IP             string
+	Port           string
+	APIKey         string
+}
+
+// DiscoveredPrinter is a printer that answered a discovery broadcast on makerbotd's network
+type DiscoveredPrinter struct {
+	IP          string        `json:"ip"`
+	Port        string        `json:"port"`
+	Serial      string        `json:"serial"`
+	MachineName string        `json:"machine_name"`
+	Config      PrinterConfig `json:"config"`
+}
+
+// Spool is a filament spool in makerbotd's inventory. Lengths are in millimeters.
+type Spool struct {
+	ID              string  `json:"id"`
+	Material        string  `json:"material"`
+	Color           string  `json:"color"`
+	Vendor          string  `json:"vendor"`
+	InitialLength   float64 `json:"initial_length"`
+	RemainingLength float64 `json:"remaining_length"`
+	Printer         string  `json:"printer,omitempty"`
+	ToolIndex       *int    `json:"tool_index,omitempty"`
+}
+
+// TransferProgress is how far along a print file transfer to the printer is
+type TransferProgress struct {
+	BytesSent int64    `json:"bytes_sent"`
+	Total     int64    `json:"total"`
+	Rate      float64  `json:"rate"` // Rate is in bytes per second
+	ETA       *float64 `json:"eta"`  // ETA is the number of seconds until the transfer is done
+}
+
+// Operation is something makerbotd is doing on a printer that takes a while
+type Operation struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Printer   string            `json:"printer"`
+	Status    string            `json:"status"`
+	Step      string            `json:"step"` // Step is the step the printer's process for this operation is on
+	Error     string            `json:"error"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Transfer  *TransferProgress `json:"transfer"`
+}
+
+// ProgressSample is how far along a job was at some point
+type ProgressSample struct {
+	Time        time.Time `json:"time"`
+	Step        string    `json:"step"`
+	Progress    int       `json:"progress"`
+	ElapsedTime int       `json:"elapsed_time"`
+}
+
+// JobProgress is a printer's current job along with when it started, when it
+// should be done and how it has progressed so far
+type JobProgress struct {
+	makerbot.PrinterProcess
+	StartedAt *time.Time       `json:"started_at"`
+	ETA       *time.Time       `json:"eta"`
+	History   []ProgressSample `json:"history"`
+}
+
 // Client is a client that talks to makerbotd
 type Client struct {
 	http    *http.Client
@@ -66,42 +139,66 @@ func (c *Client) httpPost(endpoint string, result interface{}) error {
 	return c.request(req, result)
 }
 
-func (c *Client) httpPostFile(endpoint string, path string, result interface{}) error {
-	file, err := os.Open(path)
+func (c *Client) httpPostJSON(endpoint string, body interface{}, result interface{}) error {
+	data, err := json.Marshal(body)
 	if err != nil {
 		return err
 	}
 
-	data, err := ioutil.ReadAll(file)
+	req, err := http.NewRequest("POST", c.url(endpoint), bytes.NewReader(data))
 	if err != nil {
 		return err
 	}
 
-	stat, err := file.Stat()
-	if err != nil {
-		return err
-	}
+	req.Header.Add("Content-Type", "application/json")
 
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("printfile", stat.Name())
+	return c.request(req, result)
+}
+
+func (c *Client) httpPostFile(endpoint string, path string, result interface{}) error {
+	file, err := os.Open(path)
 	if err != nil {
 		return err
 	}
-	part.Write(data)
 
-	err = writer.Close()
+	stat, err := file.Stat()
 	if err != nil {
+		file.Close()
 		return err
 	}
 
+	body, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
 	req, err := http.NewRequest("POST", c.url(endpoint), body)
 	if err != nil {
+		file.Close()
 		return err
 	}
 
 	req.Header.Add("Content-Type", writer.FormDataContentType())
 
+	// Stream the file instead of reading it into memory. Sending the size first
+	// lets makerbotd refuse files that are too large before they are uploaded.
+	go func() {
+		defer file.Close()
+
+		err := writer.WriteField("size", strconv.FormatInt(stat.Size(), 10))
+		if err == nil {
+			var part io.Writer
+			part, err = writer.CreateFormFile("printfile", stat.Name())
+			if err == nil {
+				_, err = io.Copy(part, file)
+			}
+		}
+
+		if err == nil {
+			err = writer.Close()
+		}
+
+		pw.CloseWithError(err)
+	}()
+
 	return c.request(req, result)
 }
 
@@ -126,6 +223,18 @@ func (c *Client) GetPrinters() (*[]makerbot.Printer, error) {
 	return &printers, nil
 }
 
+// GetPrintersWithTag gets a list of connected printers that were configured with `tag`
+func (c *Client) GetPrintersWithTag(tag string) (*[]makerbot.Printer, error) {
+	var printers []makerbot.Printer
+
+	err := c.httpGet("/api/v1/printers?tag="+url.QueryEscape(tag), &printers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &printers, nil
+}
+
 // GetPrinter gets a printer with `id`
 func (c *Client) GetPrinter(id string) (*makerbot.Printer, error) {
 	var printer makerbot.Printer
@@ -138,6 +247,47 @@ func (c *Client) GetPrinter(id string) (*makerbot.Printer, error) {
 	return &printer, nil
 }
 
+// WaitForPrinterChange blocks for up to `wait` until the state of the printer with
+// `id` is newer than revision `since`, then returns the printer and its current
+// revision. If nothing changed, the same revision is returned. Pass 0 as `since`
+// to get the current state and revision right away.
+func (c *Client) WaitForPrinterChange(id string, since uint64, wait time.Duration) (*makerbot.Printer, uint64, error) {
+	endpoint := "/api/v1/printers/" + id
+	if since > 0 {
+		endpoint += fmt.Sprintf("?since=%d&wait=%s", since, wait)
+	}
+
+	req, err := http.NewRequest("GET", c.url(endpoint), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	var ar apiResult
+	json.NewDecoder(res.Body).Decode(&ar)
+	if ar.Error != nil {
+		return nil, 0, errors.New(*ar.Error)
+	}
+
+	rev, err := strconv.ParseUint(res.Header.Get("X-Revision"), 10, 64)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var printer makerbot.Printer
+	err = json.Unmarshal(ar.Result, &printer)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &printer, rev, nil
+}
+
 // GetPrinterSnapshot gets a single frame from the printer's camera
 func (c *Client) GetPrinterSnapshot(id string) (*[]byte, error) {
 	req, err := http.NewRequest("GET", c.url(fmt.Sprintf("/api/v1/printers/%s/snapshot.jpg?%d", id, time.Now().Unix())), nil)
@@ -159,9 +309,63 @@ func (c *Client) GetPrinterSnapshot(id string) (*[]byte, error) {
 	return &data, nil
 }
 
-// Print tells makerbotd to print on a specified printer
-func (c *Client) Print(id, path string) (*bool, error) {
-	var result bool
+// SnapshotOptions controls how GetPrinterSnapshotWithOptions converts the camera frame
+type SnapshotOptions struct {
+	PNG     bool // PNG requests a PNG instead of a JPEG
+	Width   int  // Width scales the image, keeping the aspect ratio. 0 keeps the original size.
+	Quality int  // Quality is the JPEG quality, 1-100. 0 uses the default.
+	Rotate  int  // Rotate rotates the image clockwise by 90, 180 or 270 degrees
+}
+
+// GetPrinterSnapshotWithOptions gets a single frame from the printer's camera, converted as described by `opts`
+func (c *Client) GetPrinterSnapshotWithOptions(id string, opts SnapshotOptions) (*[]byte, error) {
+	ext := "jpg"
+	if opts.PNG {
+		ext = "png"
+	}
+
+	q := url.Values{}
+	q.Set("t", strconv.FormatInt(time.Now().Unix(), 10))
+
+	if opts.Width > 0 {
+		q.Set("width", strconv.Itoa(opts.Width))
+	}
+
+	if opts.Quality > 0 {
+		q.Set("quality", strconv.Itoa(opts.Quality))
+	}
+
+	if opts.Rotate != 0 {
+		q.Set("rotate", strconv.Itoa(opts.Rotate))
+	}
+
+	req, err := http.NewRequest("GET", c.url(fmt.Sprintf("/api/v1/printers/%s/snapshot.%s?%s", id, ext, q.Encode())), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("makerbotd returned %s", res.Status)
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// Print tells makerbotd to print on a specified printer. The returned operation follows the print.
+// The upload has to finish within an hour, after which makerbotd stops reading it.
+func (c *Client) Print(id, path string) (*Operation, error) {
+	var result Operation
 
 	err := c.httpPostFile("/api/v1/printers/"+id+"/prints", path, &result)
 	if err != nil {
@@ -171,6 +375,19 @@ func (c *Client) Print(id, path string) (*bool, error) {
 	return &result, nil
 }
 
+// PrintURL tells makerbotd to download the file at `fileURL` and print it on a specified printer.
+// `headers` are sent along with the download request and may be nil.
+func (c *Client) PrintURL(id, fileURL string, headers map[string]string) (*Operation, error) {
+	var result Operation
+
+	err := c.httpPostJSON("/api/v1/printers/"+id+"/prints", map[string]interface{}{"url": fileURL, "headers": headers}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
 // GetCurrentJob gets a current job yeet
 func (c *Client) GetCurrentJob(id string) (*makerbot.PrinterProcess, error) {
 	var job makerbot.PrinterProcess
@@ -183,6 +400,19 @@ func (c *Client) GetCurrentJob(id string) (*makerbot.PrinterProcess, error) {
 	return &job, nil
 }
 
+// GetCurrentJobProgress gets the current job on a specified printer along with its
+// progress history and estimated completion time
+func (c *Client) GetCurrentJobProgress(id string) (*JobProgress, error) {
+	var job JobProgress
+
+	err := c.httpGet("/api/v1/printers/"+id+"/current_job", &job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
 // SuspendCurrentJob tells makerbotd to suspend the current job on a specified printer
 func (c *Client) SuspendCurrentJob(printerID string) (*bool, error) {
 	var result bool
@@ -207,6 +437,31 @@ func (c *Client) ResumeCurrentJob(printerID string) (*bool, error) {
 	return &result, nil
 }
 
+// GetCurrentJobMethods gets the process methods the current job on a specified printer accepts right now
+func (c *Client) GetCurrentJobMethods(printerID string) (*[]string, error) {
+	var methods []string
+
+	err := c.httpGet("/api/v1/printers/"+printerID+"/current_job/methods", &methods)
+	if err != nil {
+		return nil, err
+	}
+
+	return &methods, nil
+}
+
+// ProcessMethod tells makerbotd to invoke `method` on the current job on a specified printer,
+// e.g. "acknowledge_completed". It must be one of the methods from GetCurrentJobMethods.
+func (c *Client) ProcessMethod(printerID string, method string) (*bool, error) {
+	var result bool
+
+	err := c.httpPost("/api/v1/printers/"+printerID+"/current_job/process_method/"+url.PathEscape(method), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
 // CancelCurrentJob tells makerbotd to cancel the current job on a specified printer
 func (c *Client) CancelCurrentJob(printerID string) (*bool, error) {
 	var result bool
@@ -220,8 +475,8 @@ func (c *Client) CancelCurrentJob(printerID string) (*bool, error) {
 }
 
 // LoadFilament tells makerbotd to tell the specified printer to start loading filament
-func (c *Client) LoadFilament(printerID string, toolIndex string) (*bool, error) {
-	var result bool
+func (c *Client) LoadFilament(printerID string, toolIndex string) (*Operation, error) {
+	var result Operation
 
 	err := c.httpPost("/api/v1/printers/"+printerID+"/load_filament/"+toolIndex, &result)
 	if err != nil {
@@ -232,8 +487,8 @@ func (c *Client) LoadFilament(printerID string, toolIndex string) (*bool, error)
 }
 
 // UnloadFilament tells makerbotd to tell the specified printer to start unloading filament
-func (c *Client) UnloadFilament(printerID string, toolIndex string) (*bool, error) {
-	var result bool
+func (c *Client) UnloadFilament(printerID string, toolIndex string) (*Operation, error) {
+	var result Operation
 
 	err := c.httpPost("/api/v1/printers/"+printerID+"/unload_filament/"+toolIndex, &result)
 	if err != nil {
@@ -242,3 +497,125 @@ func (c *Client) UnloadFilament(printerID string, toolIndex string) (*bool, erro
 
 	return &result, nil
 }
+
+// Discover asks makerbotd to look for printers on its local network
+func (c *Client) Discover() (*[]DiscoveredPrinter, error) {
+	var printers []DiscoveredPrinter
+
+	err := c.httpGet("/api/v1/discover", &printers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &printers, nil
+}
+
+// RPC calls a raw JSON-RPC method on the specified printer. This is an admin
+// endpoint, so `adminToken` must match makerbotd's AdminToken.
+func (c *Client) RPC(printerID, adminToken, method string, params interface{}) (*json.RawMessage, error) {
+	body, err := json.Marshal(map[string]interface{}{"method": method, "params": params})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", c.url("/api/v1/printers/"+printerID+"/rpc"), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+
+	var result json.RawMessage
+
+	err = c.request(req, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// LoadFilamentWithSpool is like LoadFilament, but also records that `spoolID` is being loaded into the tool
+func (c *Client) LoadFilamentWithSpool(printerID string, toolIndex string, spoolID string) (*Operation, error) {
+	var result Operation
+
+	err := c.httpPost("/api/v1/printers/"+printerID+"/load_filament/"+toolIndex+"?spool="+url.QueryEscape(spoolID), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetSpools gets every spool in makerbotd's inventory
+func (c *Client) GetSpools() (*[]Spool, error) {
+	var spools []Spool
+
+	err := c.httpGet("/api/v1/spools", &spools)
+	if err != nil {
+		return nil, err
+	}
+
+	return &spools, nil
+}
+
+// GetPrinterSpools gets the spools that are loaded into a specified printer
+func (c *Client) GetPrinterSpools(printerID string) (*[]Spool, error) {
+	var spools []Spool
+
+	err := c.httpGet("/api/v1/printers/"+printerID+"/spools", &spools)
+	if err != nil {
+		return nil, err
+	}
+
+	return &spools, nil
+}
+
+// AddSpool adds a spool to makerbotd's inventory
+func (c *Client) AddSpool(spool Spool) (*Spool, error) {
+	var result Spool
+
+	err := c.httpPostJSON("/api/v1/spools", spool, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// DeleteSpool removes a spool from makerbotd's inventory
+func (c *Client) DeleteSpool(spoolID string) (*bool, error) {
+	var result bool
+
+	err := c.httpDelete("/api/v1/spools/"+spoolID, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetOperation gets an operation by its ID
+func (c *Client) GetOperation(operationID string) (*Operation, error) {
+	var op Operation
+
+	err := c.httpGet("/api/v1/operations/"+operationID, &op)
+	if err != nil {
+		return nil, err
+	}
+
+	return &op, nil
+}
+
+// GetPrinterOperations gets the running and recently finished operations on a specified printer
+func (c *Client) GetPrinterOperations(printerID string) (*[]Operation, error) {
+	var ops []Operation
+
+	err := c.httpGet("/api/v1/printers/"+printerID+"/operations", &ops)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ops, nil
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+// APIOctoPrint implements the subset of the OctoPrint REST API that slicers like
+// Cura and PrusaSlicer use to send a file straight to a printer. Requests to
+// /api/... go to OctoPrintPrinter, and requests to /octoprint/:id/api/... go to
+// the printer with that ID.
+type APIOctoPrint struct {
+	context *mbContext
+}
+
+var _ API = (*APIOctoPrint)(nil)
+
+type octoPrintFile struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Origin string `json:"origin"`
+}
+
+type octoPrintJob struct {
+	Job struct {
+		File               octoPrintFile `json:"file"`
+		EstimatedPrintTime *int          `json:"estimatedPrintTime"`
+	} `json:"job"`
+	Progress struct {
+		Completion    *int `json:"completion"`
+		PrintTime     *int `json:"printTime"`
+		PrintTimeLeft *int `json:"printTimeLeft"`
+	} `json:"progress"`
+	State string `json:"state"`
+}
+
+// Route implements API.Route
+func (a *APIOctoPrint) Route(router *instrumentedRouter) {
+	for _, prefix := range []string{"/api/", "/octoprint/:id/api/"} {
+		router.GET(prefix+"version", a.getVersion)
+		router.GET(prefix+"job", a.getJob)
+
+		if !a.context.Config.ReadOnly {
+			router.POST(prefix+"files/local", a.postFilesLocal)
+		}
+	}
+}
+
+func (a *APIOctoPrint) error(w http.ResponseWriter, msg string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	enc := json.NewEncoder(w)
+	enc.Encode(map[string]string{"error": msg})
+}
+
+// authorized checks the X-Api-Key header slicers send against OctoPrintAPIKey
+func (a *APIOctoPrint) authorized(w http.ResponseWriter, r *http.Request) bool {
+	key := a.context.Config.OctoPrintAPIKey
+	if key == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Api-Key")), []byte(key)) == 1 {
+		return true
+	}
+
+	a.error(w, "Invalid API key", http.StatusForbidden)
+	return false
+}
+
+func (a *APIOctoPrint) findPrinter(params httprouter.Params) (*printerConnection, bool) {
+	id := params.ByName("id")
+	if id == "" {
+		id = a.context.Config.OctoPrintPrinter
+	}
+
+	return a.context.Printers.Find(id)
+}
+
+func (a *APIOctoPrint) getVersion(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !a.authorized(w, r) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	enc.Encode(map[string]string{
+		"api":    "0.1",
+		"server": "1.5.0",
+		"text":   "OctoPrint 1.5.0 (makerbotd)",
+	})
+}
+
+// octoPrintState translates the printer's current process into an OctoPrint
+// state. `state` is nil if the printer isn't connected.
+func octoPrintState(state *makerbot.Printer) string {
+	if state == nil {
+		return "Offline"
+	}
+
+	md := state.Metadata
+	if md == nil || md.CurrentProcess == nil || md.CurrentProcess.Complete {
+		return "Operational"
+	}
+
+	switch md.CurrentProcess.Step {
+	case "suspended", "suspending":
+		return "Paused"
+	case "cancelling":
+		return "Cancelling"
+	case "failed", "error":
+		return "Error"
+	}
+
+	return "Printing"
+}
+
+func (a *APIOctoPrint) getJob(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if !a.authorized(w, r) {
+		return
+	}
+
+	printer, ok := a.findPrinter(params)
+	if !ok {
+		a.error(w, "Printer not found", http.StatusNotFound)
+		return
+	}
+
+	// The state and the job are both read from the same snapshot, so the
+	// process can't go away in between
+	_, state := printer.State()
+
+	var job octoPrintJob
+	job.State = octoPrintState(state)
+
+	if job.State != "Offline" && job.State != "Operational" {
+		proc := state.Metadata.CurrentProcess
+
+		job.Job.File = octoPrintFile{Name: proc.Filename, Path: proc.FilePath, Origin: "local"}
+
+		completion := proc.Progress
+		printTime := proc.ElapsedTime
+		job.Progress.Completion = &completion
+		job.Progress.PrintTime = &printTime
+
+		if proc.TimeEstimation > 0 {
+			estimate := proc.TimeEstimation
+			left := estimate - printTime
+			if left < 0 {
+				left = 0
+			}
+
+			job.Job.EstimatedPrintTime = &estimate
+			job.Progress.PrintTimeLeft = &left
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	enc.Encode(job)
+}
+
+func (a *APIOctoPrint) postFilesLocal(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if !a.authorized(w, r) {
+		return
+	}
+
+	printer, ok := a.findPrinter(params)
+	if !ok {
+		a.error(w, "Printer not found", http.StatusNotFound)
+		return
+	}
+
+	if printer.backend() == nil {
+		a.error(w, "Printer is not operational", http.StatusConflict)
+		return
+	}
+
+	allowSlowUpload(w)
+
+	max := a.context.maxUploadSize()
+	r.Body = http.MaxBytesReader(w, r.Body, max+1024*1024)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		a.error(w, "Could not parse upload", http.StatusBadRequest)
+		return
+	}
+
+	// The file is spooled to disk like any other upload, so it isn't held in
+	// memory and can be as large as MaxUploadSize
+	var file *os.File
+	var name string
+	var size int64
+	print, _ := strconv.ParseBool(r.URL.Query().Get("print"))
+
+	// Print takes the file over, so it is only discarded here if it isn't sent
+	defer func() {
+		if file != nil {
+			discardUpload(file)
+		}
+	}()
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			a.error(w, "Could not parse upload", http.StatusBadRequest)
+			return
+		}
+
+		switch part.FormName() {
+		case "print":
+			data, _ := ioutil.ReadAll(io.LimitReader(part, 8))
+			print, _ = strconv.ParseBool(string(data))
+
+		case "file":
+			if file != nil {
+				continue
+			}
+
+			name = path.Base(part.FileName())
+
+			file, size, err = spoolUpload(part, max)
+			if err == errUploadTooLarge {
+				a.error(w, fmt.Sprintf("Files can be at most %d bytes", max), http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			if err != nil {
+				a.error(w, "Could not parse upload", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	// makerbotd has no file storage of its own, so uploads can only be printed
+	if !print {
+		a.error(w, "makerbotd can only print uploads directly, set print=true", http.StatusBadRequest)
+		return
+	}
+
+	if file == nil {
+		a.error(w, "No file included", http.StatusBadRequest)
+		return
+	}
+
+	// printUpload takes the file over, whether or not it prints it
+	upload := file
+	file = nil
+
+	_, warning, err := a.context.printUpload(printer, name, upload, size)
+	if err != nil {
+		a.error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if warning != "" {
+		addWarning(w, warning)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	res := map[string]interface{}{
+		"done": true,
+		"files": map[string]interface{}{
+			"local": octoPrintFile{Name: name, Path: name, Origin: "local"},
+		},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.Encode(res)
+}
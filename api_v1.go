@@ -1,11 +1,16 @@
 package main
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -34,16 +39,31 @@ func (e apiResult) MarshalJSON() ([]byte, error) {
 // APIv1 is version 1 of the API
 type APIv1 struct {
 	context *mbContext
+	routes  []string // routes are the routes Route registered, as "METHOD /path"
 }
 
+var _ API = (*APIv1)(nil)
+
 // Route implements API.Route
-func (a *APIv1) Route(router *httprouter.Router) {
+func (a *APIv1) Route(r *instrumentedRouter) {
 	prefix := "/api/v1/"
+	router := &documentedRouter{instrumentedRouter: r, registered: &a.routes}
 
+	router.GET(prefix+"openapi.json", a.getOpenAPI)
 	router.GET(prefix+"printers", a.getPrinters)
 	router.GET(prefix+"printers/:id", a.getPrinter)
 	router.GET(prefix+"printers/:id/snapshot.jpg", a.getPrinterSnapshot)
+	router.GET(prefix+"printers/:id/snapshot.png", a.getPrinterSnapshotPNG)
 	router.GET(prefix+"printers/:id/current_job", a.getPrinterCurrentJob)
+	router.GET(prefix+"printers/:id/current_job/methods", a.getPrinterCurrentJobMethods)
+	router.GET(prefix+"printers/:id/spools", a.getPrinterSpools)
+	router.GET(prefix+"discover", a.getDiscover)
+	router.GET(prefix+"spools", a.getSpools)
+	router.GET(prefix+"spools/:spool_id", a.getSpool)
+	router.GET(prefix+"printers/:id/operations", a.getPrinterOperations)
+	router.GET(prefix+"operations", a.getOperations)
+	router.GET(prefix+"operations/:operation_id", a.getOperation)
+	router.GET(prefix+"operations/:operation_id/events", a.getOperationEvents)
 
 	// TODO: Handle this somewhere else so it returns the proper HTTP status code
 	// instead of just a 404
@@ -51,11 +71,18 @@ func (a *APIv1) Route(router *httprouter.Router) {
 		// Endpoints that will result in a mutation
 		router.POST(prefix+"printers/:id/current_job/suspend", a.postPrinterCurrentJobSuspend)
 		router.POST(prefix+"printers/:id/current_job/resume", a.postPrinterCurrentJobResume)
-		router.POST(prefix+"printers/:id/current_job/process_method/:method", a.deletePrinterCurrentJob)
+		router.POST(prefix+"printers/:id/current_job/process_method/:method", a.postPrinterCurrentJobMethod)
 		router.DELETE(prefix+"printers/:id/current_job", a.deletePrinterCurrentJob)
 		router.POST(prefix+"printers/:id/prints", a.postPrinterPrints)
 		router.POST(prefix+"printers/:id/unload_filament/:tool_index", a.postPrinterUnloadFilament)
 		router.POST(prefix+"printers/:id/load_filament/:tool_index", a.postPrinterLoadFilament)
+		router.POST(prefix+"spools", a.postSpools)
+		router.DELETE(prefix+"spools/:spool_id", a.deleteSpool)
+
+		if a.context.Config.AdminToken != "" {
+			// Admin endpoints
+			router.POST(prefix+"printers/:id/rpc", a.postPrinterRPC)
+		}
 	}
 }
 
@@ -69,80 +96,268 @@ func (a *APIv1) badRequest(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, string(nf), http.StatusBadRequest)
 }
 
+func (a *APIv1) unavailable(w http.ResponseWriter, r *http.Request) {
+	nf, _ := json.Marshal(apiError(errPrinterNotConnected))
+	http.Error(w, string(nf), http.StatusServiceUnavailable)
+}
+
+func (a *APIv1) conflict(w http.ResponseWriter, r *http.Request, err error) {
+	nf, _ := json.Marshal(apiError(err))
+	http.Error(w, string(nf), http.StatusConflict)
+}
+
+func (a *APIv1) forbidden(w http.ResponseWriter, r *http.Request) {
+	nf, _ := json.Marshal(apiError(errors.New("forbidden")))
+	http.Error(w, string(nf), http.StatusForbidden)
+}
+
+func (a *APIv1) notImplemented(w http.ResponseWriter, r *http.Request, err error) {
+	nf, _ := json.Marshal(apiError(err))
+	http.Error(w, string(nf), http.StatusNotImplemented)
+}
+
+// admin returns true if the request carries the AdminToken as a bearer token.
+// Otherwise a 403 is written.
+func (a *APIv1) admin(w http.ResponseWriter, r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.context.Config.AdminToken)) == 1 {
+		return true
+	}
+
+	a.forbidden(w, r)
+	return false
+}
+
+// accepted writes a 202 pointing to the operation that is carrying out the request
+func (a *APIv1) accepted(w http.ResponseWriter, r *http.Request, op operation) {
+	w.Header().Set("Location", "/api/v1/operations/"+op.ID)
+	w.WriteHeader(http.StatusAccepted)
+
+	enc := json.NewEncoder(w)
+	enc.Encode(apiSuccess(op))
+}
+
+func (a *APIv1) badRequestError(w http.ResponseWriter, r *http.Request, err error) {
+	nf, _ := json.Marshal(apiError(err))
+	http.Error(w, string(nf), http.StatusBadRequest)
+}
+
 func (a *APIv1) internalError(w http.ResponseWriter, r *http.Request) {
 	nf, _ := json.Marshal(apiError(errors.New("internal server error")))
 	http.Error(w, string(nf), http.StatusInternalServerError)
 }
 
-func (a *APIv1) getPrinters(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+// findPrinter looks up the printer from the `id` param along with its
+// connection, which handlers should use rather than reading it again. If the
+// printer can't be found or is not connected right now, an error is written and
+// ok is false.
+func (a *APIv1) findPrinter(w http.ResponseWriter, r *http.Request, params httprouter.Params) (printer *printerConnection, conn printerBackend, ok bool) {
+	printer, ok = a.context.Printers.Find(params.ByName("id"))
+	if !ok {
+		a.notFound(w, r)
+		return nil, nil, false
+	}
+
+	conn = printer.backend()
+	if conn == nil {
+		a.unavailable(w, r)
+		return nil, nil, false
+	}
+
+	return printer, conn, true
+}
+
+func (a *APIv1) getPrinters(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	enc.Encode(apiSuccess(a.context.Printers.ConnectedPrinters(r.URL.Query().Get("tag"))))
+}
+
+func (a *APIv1) getDiscover(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
+	timeout := discoveryTimeout
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		var err error
+		timeout, err = time.ParseDuration(t)
+		if err != nil || timeout <= 0 || timeout > 30*time.Second {
+			a.badRequest(w, r)
+			return
+		}
+	}
+
 	enc := json.NewEncoder(w)
-	enc.Encode(apiSuccess(a.context.Printers.ConnectedPrinters()))
+
+	printers, err := discoverPrinters(discoveryAddress, timeout)
+	if err != nil {
+		enc.Encode(apiError(err))
+		return
+	}
+
+	enc.Encode(apiSuccess(printers))
 }
 
 func (a *APIv1) getPrinter(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
-	printer, ok := a.context.Printers.Find(params.ByName("id"))
+	q := r.URL.Query()
+
+	var wait time.Duration
+	var since uint64
+
+	if q.Get("wait") != "" {
+		var err error
+
+		wait, err = time.ParseDuration(q.Get("wait"))
+		if err != nil || wait < 0 || wait > maxStateWait {
+			a.badRequestError(w, r, fmt.Errorf("wait must be a duration up to %s", maxStateWait))
+			return
+		}
+
+		since, err = strconv.ParseUint(q.Get("since"), 10, 64)
+		if err != nil {
+			a.badRequestError(w, r, errors.New("since must be a revision when waiting"))
+			return
+		}
+	}
+
+	printer, _, ok := a.findPrinter(w, r, params)
 	if !ok {
-		a.notFound(w, r)
+		return
+	}
+
+	if wait > 0 {
+		printer.WaitForRevision(since, wait, r.Context().Done())
+	}
+
+	rev, state := printer.State()
+	if state == nil {
+		// The printer might have gone away while we were waiting
+		a.unavailable(w, r)
+		return
+	}
+
+	etag := revisionETag(rev)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("X-Revision", strconv.FormatUint(rev, 10))
+
+	if notModified(r, etag, time.Time{}) {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	enc := json.NewEncoder(w)
-	enc.Encode(apiSuccess(printer.connection.Printer))
+	enc.Encode(apiSuccess(state))
 }
 
 func (a *APIv1) getPrinterSnapshot(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	a.serveSnapshot(w, r, params, snapshotFormatJPEG)
+}
+
+func (a *APIv1) getPrinterSnapshotPNG(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	a.serveSnapshot(w, r, params, snapshotFormatPNG)
+}
+
+func (a *APIv1) serveSnapshot(w http.ResponseWriter, r *http.Request, params httprouter.Params, format string) {
 	w.Header().Set("Content-Type", "application/json")
 
-	printer, ok := a.context.Printers.Find(params.ByName("id"))
+	opts, err := parseSnapshotOptions(r.URL.Query(), format)
+	if err != nil {
+		a.badRequestError(w, r, err)
+		return
+	}
+
+	printer, _, ok := a.findPrinter(w, r, params)
 	if !ok {
-		a.notFound(w, r)
 		return
 	}
 
-	frame, err := printer.connection.GetCameraFrame()
+	frame, err := printer.CameraFrame()
+	if err == errPrinterNotConnected {
+		a.unavailable(w, r)
+		return
+	}
+
+	if err != nil {
+		a.internalError(w, r)
+		return
+	}
+
+	etag := fmt.Sprintf(`"%s-%s-%d-%d-%d"`, frame.Hash, opts.Format, opts.Width, opts.Quality, opts.Rotate)
+	modified := frame.FetchedAt.UTC().Truncate(time.Second)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if notModified(r, etag, modified) {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, contentType, err := encodeSnapshot(frame.Frame, opts)
 	if err != nil {
 		a.internalError(w, r)
 		return
 	}
 
-	w.Header().Set("Content-Type", "image/png")
-	w.Write(frame.Data)
+	w.Header().Set("Content-Type", contentType)
+	w.Write(data)
+}
+
+// notModified checks a conditional GET against the representation's ETag and
+// modification time. If-None-Match takes precedence over If-Modified-Since, which
+// is ignored if `modified` is zero.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, t := range strings.Split(inm, ",") {
+			t = strings.TrimSpace(t)
+			if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if ims, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modified.IsZero() {
+		return !modified.After(ims)
+	}
+
+	return false
 }
 
 func (a *APIv1) getPrinterCurrentJob(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
-	printer, ok := a.context.Printers.Find(params.ByName("id"))
+	printer, conn, ok := a.findPrinter(w, r, params)
 	if !ok {
-		a.notFound(w, r)
 		return
 	}
 
-	if printer.connection.Printer.Metadata == nil {
+	if conn.Printer().Metadata == nil {
 		fmt.Fprintf(w, "null\n")
 		return
 	}
 
 	enc := json.NewEncoder(w)
-	enc.Encode(apiSuccess(printer.connection.Printer.Metadata.CurrentProcess))
+	enc.Encode(apiSuccess(printer.JobProgress()))
 }
 
 func (a *APIv1) postPrinterCurrentJobSuspend(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
-	printer, ok := a.context.Printers.Find(params.ByName("id"))
+	_, conn, ok := a.findPrinter(w, r, params)
 	if !ok {
-		a.notFound(w, r)
 		return
 	}
 
 	enc := json.NewEncoder(w)
 
-	_, err := printer.connection.Suspend()
+	err := conn.Suspend()
 	if err != nil {
 		enc.Encode(apiError(err))
 		return
@@ -154,15 +369,14 @@ func (a *APIv1) postPrinterCurrentJobSuspend(w http.ResponseWriter, r *http.Requ
 func (a *APIv1) postPrinterCurrentJobResume(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
-	printer, ok := a.context.Printers.Find(params.ByName("id"))
+	_, conn, ok := a.findPrinter(w, r, params)
 	if !ok {
-		a.notFound(w, r)
 		return
 	}
 
 	enc := json.NewEncoder(w)
 
-	_, err := printer.connection.Resume()
+	err := conn.Resume()
 	if err != nil {
 		enc.Encode(apiError(err))
 		return
@@ -171,18 +385,62 @@ func (a *APIv1) postPrinterCurrentJobResume(w http.ResponseWriter, r *http.Reque
 	enc.Encode(apiSuccess(true))
 }
 
+// currentMethods returns the methods the printer's current process accepts right now
+func currentMethods(printer *printerConnection) []string {
+	_, state := printer.State()
+	if state == nil || state.Metadata == nil || state.Metadata.CurrentProcess == nil || state.Metadata.CurrentProcess.Methods == nil {
+		return []string{}
+	}
+
+	return state.Metadata.CurrentProcess.Methods
+}
+
+func (a *APIv1) getPrinterCurrentJobMethods(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	printer, _, ok := a.findPrinter(w, r, params)
+	if !ok {
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	enc.Encode(apiSuccess(currentMethods(printer)))
+}
+
 func (a *APIv1) postPrinterCurrentJobMethod(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
-	printer, ok := a.context.Printers.Find(params.ByName("id"))
+	printer, conn, ok := a.findPrinter(w, r, params)
 	if !ok {
-		a.notFound(w, r)
+		return
+	}
+
+	proc := printer.currentProcess()
+	if proc == nil {
+		nf, _ := json.Marshal(apiError(errors.New("no job is running")))
+		http.Error(w, string(nf), http.StatusNotFound)
+		return
+	}
+
+	method := params.ByName("method")
+	methods := proc.Methods
+
+	valid := false
+	for _, m := range methods {
+		if m == method {
+			valid = true
+			break
+		}
+	}
+
+	if !valid {
+		a.conflict(w, r, fmt.Errorf("method %q is not available right now, available methods: %s", method, strings.Join(methods, ", ")))
 		return
 	}
 
 	enc := json.NewEncoder(w)
 
-	_, err := printer.connection.ProcessMethod(params.ByName("method"))
+	err := conn.ProcessMethod(method)
 	if err != nil {
 		enc.Encode(apiError(err))
 		return
@@ -194,15 +452,14 @@ func (a *APIv1) postPrinterCurrentJobMethod(w http.ResponseWriter, r *http.Reque
 func (a *APIv1) deletePrinterCurrentJob(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
-	printer, ok := a.context.Printers.Find(params.ByName("id"))
+	_, conn, ok := a.findPrinter(w, r, params)
 	if !ok {
-		a.notFound(w, r)
 		return
 	}
 
 	enc := json.NewEncoder(w)
 
-	_, err := printer.connection.Cancel()
+	err := conn.Cancel()
 	if err != nil {
 		enc.Encode(apiError(err))
 		return
@@ -214,42 +471,48 @@ func (a *APIv1) deletePrinterCurrentJob(w http.ResponseWriter, r *http.Request,
 func (a *APIv1) postPrinterPrints(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
-	printer, ok := a.context.Printers.Find(params.ByName("id"))
+	printer, _, ok := a.findPrinter(w, r, params)
 	if !ok {
-		a.notFound(w, r)
 		return
 	}
 
-	err := r.ParseMultipartForm(52428800)
-	if err != nil {
-		a.internalError(w, r)
-		return
+	allowSlowUpload(w)
+
+	r.Body = http.MaxBytesReader(w, r.Body, a.context.maxUploadSize()+1024*1024)
+
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mt {
+	case "application/json":
+		a.printFromURL(w, r, printer)
+	case "multipart/form-data":
+		a.printMultipart(w, r, printer)
+	default:
+		a.printRawBody(w, r, printer)
 	}
+}
 
-	file, meta, err := r.FormFile("printfile")
+// sendPrint sends `file`, a spooled upload, to the printer after checking it
+// against the loaded spools, and writes the operation sending it. The file is
+// discarded if it isn't sent.
+func (a *APIv1) sendPrint(w http.ResponseWriter, r *http.Request, printer *printerConnection, name string, file *os.File, size int64) {
+	op, warning, err := a.context.printUpload(printer, name, file, size)
 	if err != nil {
-		a.badRequest(w, r)
+		a.conflict(w, r, err)
 		return
 	}
-	defer file.Close()
-
-	enc := json.NewEncoder(w)
 
-	err = printer.connection.Print(meta.Filename, file, int(meta.Size))
-	if err != nil {
-		enc.Encode(apiError(err))
-		return
+	if warning != "" {
+		addWarning(w, warning)
 	}
 
-	enc.Encode(apiSuccess(true))
+	a.accepted(w, r, op)
 }
 
 func (a *APIv1) postPrinterUnloadFilament(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
-	printer, ok := a.context.Printers.Find(params.ByName("id"))
+	printer, _, ok := a.findPrinter(w, r, params)
 	if !ok {
-		a.notFound(w, r)
 		return
 	}
 
@@ -259,23 +522,23 @@ func (a *APIv1) postPrinterUnloadFilament(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	enc := json.NewEncoder(w)
+	op := printer.StartProcess(operationTypeUnloadFilament, func(conn printerBackend) error {
+		err := conn.UnloadFilament(ti)
+		if err != nil {
+			return err
+		}
 
-	_, err = printer.connection.UnloadFilament(ti)
-	if err != nil {
-		enc.Encode(apiError(err))
-		return
-	}
+		return a.context.Spools.Unassign(printer.ID(), ti)
+	})
 
-	enc.Encode(apiSuccess(true))
+	a.accepted(w, r, op)
 }
 
 func (a *APIv1) postPrinterLoadFilament(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
-	printer, ok := a.context.Printers.Find(params.ByName("id"))
+	printer, _, ok := a.findPrinter(w, r, params)
 	if !ok {
-		a.notFound(w, r)
 		return
 	}
 
@@ -285,13 +548,66 @@ func (a *APIv1) postPrinterLoadFilament(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	// The spool being loaded can optionally be given so it is tracked
+	spoolID := r.URL.Query().Get("spool")
+	if _, ok := a.context.Spools.Get(spoolID); spoolID != "" && !ok {
+		a.notFound(w, r)
+		return
+	}
+
+	op := printer.StartProcess(operationTypeLoadFilament, func(conn printerBackend) error {
+		err := conn.LoadFilament(ti)
+		if err != nil || spoolID == "" {
+			return err
+		}
+
+		return a.context.Spools.Assign(spoolID, printer.ID(), ti)
+	})
+
+	a.accepted(w, r, op)
+}
+
+func (a *APIv1) postPrinterRPC(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !a.admin(w, r) {
+		return
+	}
+
+	_, conn, ok := a.findPrinter(w, r, params)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.Method == "" {
+		a.badRequest(w, r)
+		return
+	}
+
+	caller, ok := conn.(rpcBackend)
+	if !ok {
+		a.notImplemented(w, r, errRawRPCUnsupported)
+		return
+	}
+
 	enc := json.NewEncoder(w)
 
-	_, err = printer.connection.LoadFilament(ti)
+	result, err := caller.Call(req.Method, req.Params)
+	if err == errRawRPCUnsupported {
+		a.notImplemented(w, r, err)
+		return
+	}
+
 	if err != nil {
 		enc.Encode(apiError(err))
 		return
 	}
 
-	enc.Encode(apiSuccess(true))
+	enc.Encode(apiSuccess(result))
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func (a *APIv1) getOperations(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	enc.Encode(apiSuccess(a.context.Operations.List("")))
+}
+
+func (a *APIv1) getPrinterOperations(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Operations outlive connections, so they can be listed while disconnected
+	printer, ok := a.context.Printers.Find(params.ByName("id"))
+	if !ok {
+		a.notFound(w, r)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	enc.Encode(apiSuccess(a.context.Operations.List(printer.ID())))
+}
+
+func (a *APIv1) getOperation(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	op, ok := a.context.Operations.Get(params.ByName("operation_id"))
+	if !ok {
+		a.notFound(w, r)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	enc.Encode(apiSuccess(op))
+}
+
+// getOperationEvents streams an operation's state as server-sent events until
+// it is done or the client goes away
+func (a *APIv1) getOperationEvents(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		a.internalError(w, r)
+		return
+	}
+
+	updates, unsubscribe, ok := a.context.Operations.Subscribe(params.ByName("operation_id"))
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		a.notFound(w, r)
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case op, open := <-updates:
+			if !open {
+				return
+			}
+
+			data, _ := json.Marshal(op)
+			fmt.Fprintf(w, "event: operation\ndata: %s\n\n", data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-a.context.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// filamentShortfall compares the filament a print needs with what is left on the
+// spools loaded into the printer. If there isn't enough, it says how much is missing.
+func (ctx *mbContext) filamentShortfall(printer *printerConnection, usage []float64) string {
+	short := ctx.Spools.Shortfalls(printer.ID(), usage)
+	if len(short) == 0 {
+		return ""
+	}
+
+	msgs := []string{}
+	for tool, mm := range short {
+		msgs = append(msgs, fmt.Sprintf("tool %d is %.0fmm short", tool, mm))
+	}
+
+	return "not enough filament loaded: " + strings.Join(msgs, ", ")
+}
+
+// preparePrint reads what `file` needs and checks it against the spools loaded
+// into `printer`. If there isn't enough filament the shortfall is returned as a
+// warning, or with RefuseLowFilament the file is discarded and the shortfall is
+// returned as an error instead. Otherwise the printer remembers what the print
+// needs, for filament tracking and the job's ETA.
+func (ctx *mbContext) preparePrint(printer *printerConnection, file *os.File, size int64) (warning string, err error) {
+	var usage []float64
+	var duration float64
+	if fm, err := readMakerbotFileMeta(file, size); err == nil {
+		usage = fm.FilamentMM()
+		duration = fm.DurationSeconds
+	}
+
+	warning = ctx.filamentShortfall(printer, usage)
+	if warning != "" && ctx.Config.RefuseLowFilament {
+		discardUpload(file)
+		return "", errors.New(warning)
+	}
+
+	printer.setFilamentUsage(usage)
+	printer.setExpectedDuration(duration)
+
+	return warning, nil
+}
+
+// printUpload sends `file`, a spooled upload, to the printer after checking it
+// with preparePrint
+func (ctx *mbContext) printUpload(printer *printerConnection, name string, file *os.File, size int64) (op operation, warning string, err error) {
+	warning, err = ctx.preparePrint(printer, file, size)
+	if err != nil {
+		return operation{}, "", err
+	}
+
+	return printer.Print(name, file, size), warning, nil
+}
+
+// addWarning adds a Warning header to the response
+func addWarning(w http.ResponseWriter, msg string) {
+	w.Header().Add("Warning", fmt.Sprintf("199 makerbotd %q", msg))
+}
+
+func (a *APIv1) getSpools(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	enc.Encode(apiSuccess(a.context.Spools.List()))
+}
+
+func (a *APIv1) getSpool(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	s, ok := a.context.Spools.Get(params.ByName("spool_id"))
+	if !ok {
+		a.notFound(w, r)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	enc.Encode(apiSuccess(s))
+}
+
+func (a *APIv1) postSpools(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var s spool
+	err := json.NewDecoder(r.Body).Decode(&s)
+	if err != nil || s.InitialLength <= 0 || s.RemainingLength < 0 {
+		a.badRequest(w, r)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+
+	s, err = a.context.Spools.Add(s)
+	if err != nil {
+		enc.Encode(apiError(err))
+		return
+	}
+
+	enc.Encode(apiSuccess(s))
+}
+
+func (a *APIv1) deleteSpool(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	err := a.context.Spools.Remove(params.ByName("spool_id"))
+	if err == errSpoolNotFound {
+		a.notFound(w, r)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+
+	if err != nil {
+		enc.Encode(apiError(err))
+		return
+	}
+
+	enc.Encode(apiSuccess(true))
+}
+
+func (a *APIv1) getPrinterSpools(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Spools stay assigned while the printer is disconnected, so don't require a connection
+	printer, ok := a.context.Printers.Find(params.ByName("id"))
+	if !ok {
+		a.notFound(w, r)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	enc.Encode(apiSuccess(a.context.Spools.LoadedIn(printer.ID())))
+}
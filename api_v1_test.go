@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+func TestProcessMethod(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	_, fb := addFakePrinter(t, ctx, printerConfig{Name: "fake", ID: "1"})
+
+	router := newInstrumentedRouter()
+	(&APIv1{context: ctx}).Route(router)
+
+	call := func(method string) int {
+		req := httptest.NewRequest("POST", "/api/v1/printers/fake/current_job/process_method/"+method, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := call("suspend"); code != http.StatusNotFound {
+		t.Errorf("got %d with no job running, want 404", code)
+	}
+
+	fb.setState(func(m *makerbot.PrinterMetadata) {
+		m.CurrentProcess = &makerbot.PrinterProcess{ID: 1, Name: "PrintProcess", Step: "printing", Methods: []string{"suspend", "cancel"}}
+	})
+
+	if code := call("resume"); code != http.StatusConflict {
+		t.Errorf("got %d for a method the job doesn't offer, want 409", code)
+	}
+
+	if code := call("suspend"); code != http.StatusOK {
+		t.Errorf("got %d for a method the job offers, want 200", code)
+	}
+
+	fb.mu.Lock()
+	methods := fb.methods
+	fb.mu.Unlock()
+
+	if len(methods) != 1 || methods[0] != "suspend" {
+		t.Errorf("called %v on the printer, want only suspend", methods)
+	}
+}
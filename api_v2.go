@@ -0,0 +1,834 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+const (
+	defaultPerPage = 50
+	maxPerPage     = 100
+)
+
+// APIv2 is the second version of the API. Resources are nested under the printer
+// they belong to, and are returned as-is instead of in an apiResult envelope.
+// Errors come with a matching HTTP status.
+type APIv2 struct {
+	context *mbContext
+	routes  []string // routes are the routes Route registered, as "METHOD /path"
+}
+
+var _ API = (*APIv2)(nil)
+
+// Route implements API.Route
+func (a *APIv2) Route(r *instrumentedRouter) {
+	prefix := "/api/v2/"
+	router := &documentedRouter{instrumentedRouter: r, registered: &a.routes}
+
+	router.GET(prefix+"openapi.json", a.getOpenAPI)
+	router.GET(prefix+"printers", a.getPrinters)
+	router.GET(prefix+"printers/:id", a.getPrinter)
+	router.GET(prefix+"printers/:id/job", a.getPrinterJob)
+	router.GET(prefix+"printers/:id/job/actions", a.getPrinterJobActions)
+	router.GET(prefix+"printers/:id/tools", a.getPrinterTools)
+	router.GET(prefix+"printers/:id/tools/:tool", a.getPrinterTool)
+	router.GET(prefix+"printers/:id/camera", a.getPrinterCamera)
+	router.GET(prefix+"printers/:id/camera/snapshot", a.getPrinterCameraSnapshot)
+	router.GET(prefix+"printers/:id/operations", a.getPrinterOperations)
+	router.GET(prefix+"discovered_printers", a.getDiscoveredPrinters)
+	router.GET(prefix+"operations", a.getOperations)
+	router.GET(prefix+"operations/:operation_id", a.getOperation)
+	router.GET(prefix+"spools", a.getSpools)
+	router.GET(prefix+"spools/:spool_id", a.getSpool)
+
+	if !a.context.Config.ReadOnly {
+		router.POST(prefix+"printers/:id/jobs", a.postPrinterJobs)
+		router.DELETE(prefix+"printers/:id/job", a.deletePrinterJob)
+		router.POST(prefix+"printers/:id/job/actions", a.postPrinterJobActions)
+		router.PUT(prefix+"printers/:id/tools/:tool/filament", a.putPrinterToolFilament)
+		router.DELETE(prefix+"printers/:id/tools/:tool/filament", a.deletePrinterToolFilament)
+		router.POST(prefix+"spools", a.postSpools)
+		router.DELETE(prefix+"spools/:spool_id", a.deleteSpool)
+	}
+}
+
+type v2Error struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// error writes an error with `status`
+func (a *APIv2) error(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	enc := json.NewEncoder(w)
+	enc.Encode(map[string]v2Error{"error": {Status: status, Message: message}})
+}
+
+// v2List is a page of a collection
+type v2List struct {
+	Items   []interface{} `json:"items"`
+	Page    int           `json:"page"`
+	PerPage int           `json:"per_page"`
+	Total   int           `json:"total"`
+}
+
+// selectFields only keeps the top-level fields of `v` listed in ?fields=
+func selectFields(r *http.Request, v interface{}) interface{} {
+	fields := r.URL.Query().Get("fields")
+	if fields == "" {
+		return v
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+
+	all := map[string]json.RawMessage{}
+	if json.Unmarshal(data, &all) != nil {
+		// Not an object, so there are no fields to select
+		return v
+	}
+
+	selected := map[string]json.RawMessage{}
+	for _, f := range strings.Split(fields, ",") {
+		if value, ok := all[strings.TrimSpace(f)]; ok {
+			selected[strings.TrimSpace(f)] = value
+		}
+	}
+
+	return selected
+}
+
+// write writes a resource with `status`, keeping only the fields in ?fields=
+func (a *APIv2) write(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	enc := json.NewEncoder(w)
+	enc.Encode(selectFields(r, v))
+}
+
+// writeList writes one page of `items`, which must be a slice, according to
+// ?page= and ?per_page=. Link headers point to the next and previous pages.
+func (a *APIv2) writeList(w http.ResponseWriter, r *http.Request, items interface{}) {
+	q := r.URL.Query()
+
+	page, perPage := 1, defaultPerPage
+
+	if p := q.Get("page"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 {
+			a.error(w, http.StatusBadRequest, "page must be a number starting at 1")
+			return
+		}
+		page = n
+	}
+
+	if p := q.Get("per_page"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > maxPerPage {
+			a.error(w, http.StatusBadRequest, fmt.Sprintf("per_page must be between 1 and %d", maxPerPage))
+			return
+		}
+		perPage = n
+	}
+
+	all := reflect.ValueOf(items)
+	total := all.Len()
+
+	start := (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+
+	list := v2List{Items: []interface{}{}, Page: page, PerPage: perPage, Total: total}
+	for i := start; i < end; i++ {
+		list.Items = append(list.Items, selectFields(r, all.Index(i).Interface()))
+	}
+
+	link := func(page int, rel string) string {
+		u := *r.URL
+		q := u.Query()
+		q.Set("page", strconv.Itoa(page))
+		q.Set("per_page", strconv.Itoa(perPage))
+		u.RawQuery = q.Encode()
+
+		return fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel)
+	}
+
+	if end < total {
+		w.Header().Add("Link", link(page+1, "next"))
+	}
+
+	if page > 1 {
+		w.Header().Add("Link", link(page-1, "prev"))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	enc.Encode(list)
+}
+
+// findPrinter looks up the printer from the `id` param. If `connected` is true
+// and the printer isn't connected right now, a 503 is written. ok is false if
+// an error was written.
+func (a *APIv2) findPrinter(w http.ResponseWriter, params httprouter.Params, connected bool) (printer *printerConnection, ok bool) {
+	printer, ok = a.context.Printers.Find(params.ByName("id"))
+	if !ok {
+		a.error(w, http.StatusNotFound, "printer not found")
+		return nil, false
+	}
+
+	if connected && printer.backend() == nil {
+		a.error(w, http.StatusServiceUnavailable, "printer is not connected")
+		return nil, false
+	}
+
+	return printer, true
+}
+
+// findTool looks up the toolhead from the `tool` param on a connected printer
+func (a *APIv2) findTool(w http.ResponseWriter, params httprouter.Params) (printer *printerConnection, tool v2Tool, ok bool) {
+	printer, ok = a.findPrinter(w, params, true)
+	if !ok {
+		return nil, tool, false
+	}
+
+	index, err := strconv.Atoi(params.ByName("tool"))
+	if err == nil {
+		for _, t := range a.tools(printer) {
+			if t.Index == index {
+				return printer, t, true
+			}
+		}
+	}
+
+	a.error(w, http.StatusNotFound, "tool not found")
+	return nil, tool, false
+}
+
+// v2Printer is a printer as APIv2 describes it, whether or not it is connected
+type v2Printer struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	Serial         string            `json:"serial"`
+	MachineName    string            `json:"machine_name"`
+	ConnectionType string            `json:"connection_type"`
+	Tags           []string          `json:"tags"`
+	Connected      bool              `json:"connected"`
+	Revision       uint64            `json:"revision"`
+	Info           *makerbot.Printer `json:"info"` // Info is what the printer reports about itself while connected
+}
+
+func newV2Printer(pc *printerConnection) v2Printer {
+	serial, machineName := pc.identity()
+
+	p := v2Printer{
+		ID:             pc.ID(),
+		Name:           pc.config.Name,
+		Serial:         serial,
+		MachineName:    machineName,
+		ConnectionType: pc.config.ConnectionType,
+		Tags:           pc.config.Tags,
+	}
+
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
+
+	// The revision, the info and whether the printer is connected all come
+	// from the same snapshot, so they agree with each other
+	p.Revision, p.Info = pc.State()
+	p.Connected = p.Info != nil
+
+	return p
+}
+
+// v2Tool is one of a printer's toolheads
+type v2Tool struct {
+	Index              int    `json:"index"`
+	CurrentTemperature int    `json:"current_temperature"`
+	TargetTemperature  int    `json:"target_temperature"`
+	FilamentPresent    bool   `json:"filament_present"`
+	ToolPresent        bool   `json:"tool_present"`
+	Spool              *spool `json:"spool"` // Spool is the spool loaded into the tool, if makerbotd knows about it
+}
+
+func (a *APIv2) tools(printer *printerConnection) []v2Tool {
+	tools := []v2Tool{}
+
+	_, state := printer.State()
+	if state == nil || state.Metadata == nil {
+		return tools
+	}
+
+	for i, t := range state.Metadata.Toolheads.Extruder {
+		tool := v2Tool{
+			Index:              i,
+			CurrentTemperature: t.CurrentTemperature,
+			TargetTemperature:  t.TargetTemperature,
+			FilamentPresent:    t.FilamentPresence,
+			ToolPresent:        t.ToolPresent,
+		}
+
+		if s, ok := a.context.Spools.Loaded(printer.ID(), i); ok {
+			tool.Spool = &s
+		}
+
+		tools = append(tools, tool)
+	}
+
+	return tools
+}
+
+// v2Camera describes a printer's camera by its latest frame
+type v2Camera struct {
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	Format    string    `json:"format"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Snapshot  string    `json:"snapshot"` // Snapshot is where to get the latest frame as an image
+}
+
+func (a *APIv2) getPrinters(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	tag := r.URL.Query().Get("tag")
+
+	a.context.Printers.RLock()
+	printers := []v2Printer{}
+	for _, pc := range a.context.Printers.list {
+		if tag == "" || pc.HasTag(tag) {
+			printers = append(printers, newV2Printer(pc))
+		}
+	}
+	a.context.Printers.RUnlock()
+
+	a.writeList(w, r, printers)
+}
+
+func (a *APIv2) getPrinter(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	printer, ok := a.findPrinter(w, params, false)
+	if !ok {
+		return
+	}
+
+	a.write(w, r, http.StatusOK, newV2Printer(printer))
+}
+
+func (a *APIv2) getPrinterJob(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	printer, ok := a.findPrinter(w, params, true)
+	if !ok {
+		return
+	}
+
+	job := printer.JobProgress()
+	if job == nil {
+		a.error(w, http.StatusNotFound, "printer has no current job")
+		return
+	}
+
+	a.write(w, r, http.StatusOK, job)
+}
+
+func (a *APIv2) getPrinterJobActions(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	printer, ok := a.findPrinter(w, params, true)
+	if !ok {
+		return
+	}
+
+	a.writeList(w, r, currentMethods(printer))
+}
+
+type v2JobAction struct {
+	Action string `json:"action"`
+}
+
+// postPrinterJobActions runs one of the actions the current job accepts right now,
+// such as "suspend" or "resume"
+func (a *APIv2) postPrinterJobActions(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var req v2JobAction
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Action == "" {
+		a.error(w, http.StatusBadRequest, "body must be a JSON object with an action")
+		return
+	}
+
+	printer, ok := a.findPrinter(w, params, true)
+	if !ok {
+		return
+	}
+
+	if printer.currentProcess() == nil {
+		a.error(w, http.StatusNotFound, "printer has no current job")
+		return
+	}
+
+	methods := currentMethods(printer)
+
+	valid := false
+	for _, m := range methods {
+		if m == req.Action {
+			valid = true
+			break
+		}
+	}
+
+	if !valid {
+		a.error(w, http.StatusConflict, fmt.Sprintf("action %q is not available right now, available actions: %s", req.Action, strings.Join(methods, ", ")))
+		return
+	}
+
+	conn := printer.backend()
+	if conn == nil {
+		a.error(w, http.StatusServiceUnavailable, errPrinterNotConnected.Error())
+		return
+	}
+
+	var err error
+	switch req.Action {
+	case "suspend":
+		err = conn.Suspend()
+	case "resume":
+		err = conn.Resume()
+	default:
+		err = conn.ProcessMethod(req.Action)
+	}
+
+	if err != nil {
+		a.error(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *APIv2) deletePrinterJob(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	printer, ok := a.findPrinter(w, params, true)
+	if !ok {
+		return
+	}
+
+	if printer.currentProcess() == nil {
+		a.error(w, http.StatusNotFound, "printer has no current job")
+		return
+	}
+
+	conn := printer.backend()
+	if conn == nil {
+		a.error(w, http.StatusServiceUnavailable, errPrinterNotConnected.Error())
+		return
+	}
+
+	err := conn.Cancel()
+	if err != nil {
+		a.error(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// postPrinterJobs starts a print. The body is either the print file itself, a
+// multipart upload with a "printfile" part, or a JSON object with the URL to
+// download the file from.
+func (a *APIv2) postPrinterJobs(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	printer, ok := a.findPrinter(w, params, true)
+	if !ok {
+		return
+	}
+
+	allowSlowUpload(w)
+
+	max := a.context.maxUploadSize()
+	r.Body = http.MaxBytesReader(w, r.Body, max+1024*1024)
+
+	var file *os.File
+	var name string
+	var size int64
+	var err error
+
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mt {
+	case "application/json":
+		var req printURLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
+			a.error(w, http.StatusBadRequest, "body must be a JSON object with a url")
+			return
+		}
+
+		if len(a.context.Config.PrintURLAllowedHosts) == 0 {
+			a.error(w, http.StatusForbidden, "printing from URLs is not enabled")
+			return
+		}
+
+		_, err := a.context.checkPrintURL(req.URL)
+		if err == errHostNotAllowed {
+			a.error(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		if err != nil {
+			a.error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		a.accepted(w, r, printer.PrintURL(req))
+		return
+	case "multipart/form-data":
+		mr, mrErr := r.MultipartReader()
+		if mrErr != nil {
+			a.error(w, http.StatusBadRequest, mrErr.Error())
+			return
+		}
+
+		for file == nil && err == nil {
+			part, partErr := mr.NextPart()
+			if partErr != nil {
+				a.error(w, http.StatusBadRequest, "printfile is required")
+				return
+			}
+
+			if part.FormName() == "printfile" {
+				name = part.FileName()
+				file, size, err = spoolUpload(part, max)
+			}
+		}
+	default:
+		name = r.URL.Query().Get("filename")
+		if _, dparams, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); name == "" && err == nil {
+			name = dparams["filename"]
+		}
+
+		if name == "" {
+			a.error(w, http.StatusBadRequest, "filename is required")
+			return
+		}
+
+		file, size, err = spoolUpload(r.Body, max)
+	}
+
+	if err == errUploadTooLarge {
+		a.error(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("print files can be at most %d bytes", max))
+		return
+	}
+
+	if err != nil {
+		a.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.startPrint(w, r, printer, path.Base(name), file, size)
+}
+
+// startPrint checks `file`, a spooled upload, against the loaded spools, sends
+// it to the printer and writes the operation following the print. The file is
+// discarded if it isn't sent.
+func (a *APIv2) startPrint(w http.ResponseWriter, r *http.Request, printer *printerConnection, name string, file *os.File, size int64) {
+	op, warning, err := a.context.printUpload(printer, name, file, size)
+	if err != nil {
+		a.error(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	if warning != "" {
+		addWarning(w, warning)
+	}
+
+	a.accepted(w, r, op)
+}
+
+// accepted writes a 202 with the operation that is carrying out the request
+func (a *APIv2) accepted(w http.ResponseWriter, r *http.Request, op operation) {
+	w.Header().Set("Location", "/api/v2/operations/"+op.ID)
+	a.write(w, r, http.StatusAccepted, op)
+}
+
+func (a *APIv2) getPrinterTools(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	printer, ok := a.findPrinter(w, params, true)
+	if !ok {
+		return
+	}
+
+	a.writeList(w, r, a.tools(printer))
+}
+
+func (a *APIv2) getPrinterTool(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	_, tool, ok := a.findTool(w, params)
+	if !ok {
+		return
+	}
+
+	a.write(w, r, http.StatusOK, tool)
+}
+
+type v2Filament struct {
+	Spool string `json:"spool"` // Spool is the ID of the spool being loaded, if it is in the inventory
+}
+
+// putPrinterToolFilament loads filament into a tool
+func (a *APIv2) putPrinterToolFilament(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var req v2Filament
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		a.error(w, http.StatusBadRequest, "body must be a JSON object")
+		return
+	}
+
+	printer, tool, ok := a.findTool(w, params)
+	if !ok {
+		return
+	}
+
+	if _, ok := a.context.Spools.Get(req.Spool); req.Spool != "" && !ok {
+		a.error(w, http.StatusUnprocessableEntity, "spool not found")
+		return
+	}
+
+	op := printer.StartProcess(operationTypeLoadFilament, func(conn printerBackend) error {
+		err := conn.LoadFilament(tool.Index)
+		if err != nil || req.Spool == "" {
+			return err
+		}
+
+		return a.context.Spools.Assign(req.Spool, printer.ID(), tool.Index)
+	})
+
+	a.accepted(w, r, op)
+}
+
+// deletePrinterToolFilament unloads filament from a tool
+func (a *APIv2) deletePrinterToolFilament(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	printer, tool, ok := a.findTool(w, params)
+	if !ok {
+		return
+	}
+
+	op := printer.StartProcess(operationTypeUnloadFilament, func(conn printerBackend) error {
+		err := conn.UnloadFilament(tool.Index)
+		if err != nil {
+			return err
+		}
+
+		return a.context.Spools.Unassign(printer.ID(), tool.Index)
+	})
+
+	a.accepted(w, r, op)
+}
+
+func (a *APIv2) getPrinterCamera(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	printer, ok := a.findPrinter(w, params, true)
+	if !ok {
+		return
+	}
+
+	frame, err := printer.CameraFrame()
+	if err == errPrinterNotConnected {
+		a.error(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	if err != nil {
+		a.error(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	camera := v2Camera{
+		Format:    "unknown",
+		FetchedAt: frame.FetchedAt,
+		Snapshot:  "/api/v2/printers/" + url.PathEscape(params.ByName("id")) + "/camera/snapshot",
+	}
+
+	if md := frame.Frame.Metadata; md != nil {
+		camera.Width = int(md.Width)
+		camera.Height = int(md.Height)
+
+		switch md.Format {
+		case cameraFrameFormatYUYV:
+			camera.Format = "yuyv"
+		case cameraFrameFormatJPEG:
+			camera.Format = "jpeg"
+		}
+	}
+
+	a.write(w, r, http.StatusOK, camera)
+}
+
+// getPrinterCameraSnapshot serves the latest camera frame as a JPEG, or a PNG if
+// ?format=png is given or it is the only image type the client accepts
+func (a *APIv2) getPrinterCameraSnapshot(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = snapshotFormatJPEG
+
+		accept := r.Header.Get("Accept")
+		if strings.Contains(accept, "image/png") && !strings.Contains(accept, "image/jpeg") {
+			format = snapshotFormatPNG
+		}
+	}
+
+	if format != snapshotFormatJPEG && format != snapshotFormatPNG {
+		a.error(w, http.StatusBadRequest, "format must be jpeg or png")
+		return
+	}
+
+	opts, err := parseSnapshotOptions(r.URL.Query(), format)
+	if err != nil {
+		a.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	printer, ok := a.findPrinter(w, params, true)
+	if !ok {
+		return
+	}
+
+	frame, err := printer.CameraFrame()
+	if err == errPrinterNotConnected {
+		a.error(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	if err != nil {
+		a.error(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	etag := fmt.Sprintf(`"%s-%s-%d-%d-%d"`, frame.Hash, opts.Format, opts.Width, opts.Quality, opts.Rotate)
+	modified := frame.FetchedAt.UTC().Truncate(time.Second)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Vary", "Accept")
+
+	if notModified(r, etag, modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, contentType, err := encodeSnapshot(frame.Frame, opts)
+	if err != nil {
+		a.error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(data)
+}
+
+func (a *APIv2) getPrinterOperations(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	printer, ok := a.findPrinter(w, params, false)
+	if !ok {
+		return
+	}
+
+	a.writeList(w, r, a.context.Operations.List(printer.ID()))
+}
+
+func (a *APIv2) getDiscoveredPrinters(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	timeout := discoveryTimeout
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		var err error
+		timeout, err = time.ParseDuration(t)
+		if err != nil || timeout <= 0 || timeout > 30*time.Second {
+			a.error(w, http.StatusBadRequest, "timeout must be a duration up to 30s")
+			return
+		}
+	}
+
+	printers, err := discoverPrinters(discoveryAddress, timeout)
+	if err != nil {
+		a.error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	a.writeList(w, r, printers)
+}
+
+func (a *APIv2) getOperations(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	a.writeList(w, r, a.context.Operations.List(""))
+}
+
+func (a *APIv2) getOperation(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	op, ok := a.context.Operations.Get(params.ByName("operation_id"))
+	if !ok {
+		a.error(w, http.StatusNotFound, "operation not found")
+		return
+	}
+
+	a.write(w, r, http.StatusOK, op)
+}
+
+func (a *APIv2) getSpools(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	a.writeList(w, r, a.context.Spools.List())
+}
+
+func (a *APIv2) getSpool(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	s, ok := a.context.Spools.Get(params.ByName("spool_id"))
+	if !ok {
+		a.error(w, http.StatusNotFound, "spool not found")
+		return
+	}
+
+	a.write(w, r, http.StatusOK, s)
+}
+
+func (a *APIv2) postSpools(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var s spool
+	err := json.NewDecoder(r.Body).Decode(&s)
+	if err != nil || s.InitialLength <= 0 || s.RemainingLength < 0 {
+		a.error(w, http.StatusBadRequest, "body must be a spool with a positive initial_length")
+		return
+	}
+
+	s, err = a.context.Spools.Add(s)
+	if err != nil {
+		a.error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", "/api/v2/spools/"+s.ID)
+	a.write(w, r, http.StatusCreated, s)
+}
+
+func (a *APIv2) deleteSpool(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	id := params.ByName("spool_id")
+	if _, ok := a.context.Spools.Get(id); !ok {
+		a.error(w, http.StatusNotFound, "spool not found")
+		return
+	}
+
+	err := a.context.Spools.Remove(id)
+	if err != nil {
+		a.error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *APIv2) getOpenAPI(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	enc.Encode(openAPIDocument("2", registeredRoutes(v2Routes, a.routes), v2Schema, v2ErrorSchema))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestV2PrinterIDIsStable(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	useFakeBackend(t, connectionTypeLocal)
+
+	pc := newPrinterConnection(ctx, printerConfig{ConnectionType: connectionTypeLocal, IP: "192.0.2.1"})
+	ctx.Printers.Add(pc)
+
+	before := newV2Printer(pc)
+	if before.ID != "192.0.2.1" || before.Connected || before.Info != nil {
+		t.Fatalf("got %+v before connecting, want the address as the ID and no info", before)
+	}
+
+	if err := pc.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	after := newV2Printer(pc)
+	if after.ID != before.ID || !after.Connected || after.Info == nil {
+		t.Fatalf("got %+v after connecting, want the same ID and info", after)
+	}
+
+	router := newInstrumentedRouter()
+	(&APIv2{context: ctx}).Route(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v2/printers/"+after.ID, nil))
+
+	var got v2Printer
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil || rec.Code != http.StatusOK || got.Serial != after.Serial {
+		t.Errorf("looking the printer up by its ID got %d %+v", rec.Code, got)
+	}
+}
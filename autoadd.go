@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultReflectorBaseURL = "https://reflector.makerbot.com"
+	defaultAutoAddInterval  = 5 * time.Minute
+)
+
+type reflectorPrinter struct {
+	ID          string `json:"id"`
+	MachineName string `json:"machine_name"`
+}
+
+// getAccountPrinters asks MakerBot Reflector at `base` for every printer
+// registered to the Thingiverse account that owns `token`
+func getAccountPrinters(base, token string) ([]reflectorPrinter, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(base, "/")+"/printers", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := http.Client{Timeout: 30 * time.Second}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reflector returned %s", res.Status)
+	}
+
+	var printers []reflectorPrinter
+	err = json.NewDecoder(res.Body).Decode(&printers)
+	if err != nil {
+		return nil, err
+	}
+
+	return printers, nil
+}
+
+// addAccountPrinters adds a remote printerConnection for every printer on the
+// account that isn't configured yet
+func (ctx *mbContext) addAccountPrinters() error {
+	base := ctx.Config.ReflectorBaseURL
+	if base == "" {
+		base = defaultReflectorBaseURL
+	}
+
+	printers, err := getAccountPrinters(base, ctx.Config.ThingiverseToken)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range printers {
+		if p.ID == "" || ctx.Printers.Has(p.ID) {
+			continue
+		}
+
+		ctx.Debugf("autoAddPrinters: adding %s (%s)\n", p.MachineName, p.ID)
+
+		conn := newPrinterConnection(ctx, printerConfig{
+			ConnectionType: connectionTypeRemote,
+			ID:             p.ID,
+		})
+		go conn.Connect()
+		ctx.Printers.Add(conn)
+	}
+
+	return nil
+}
+
+// autoAddPrinters keeps the printer list in sync with the Thingiverse account
+// by refreshing it every AutoAddInterval
+func (ctx *mbContext) autoAddPrinters() {
+	interval := defaultAutoAddInterval
+	if ctx.Config.AutoAddInterval > 0 {
+		interval = time.Duration(ctx.Config.AutoAddInterval) * time.Second
+	}
+
+	for {
+		err := ctx.addAccountPrinters()
+		if err != nil {
+			log.Printf("autoAddPrinters: could not get printers from account: %v", err)
+		}
+
+		select {
+		case <-ctx.done:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
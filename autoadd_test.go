@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newReflector starts a MakerBot Reflector stand-in that lists `printers` to
+// requests carrying `token`
+func newReflector(t *testing.T, token string, printers []reflectorPrinter) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/printers" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		json.NewEncoder(w).Encode(printers)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestGetAccountPrinters(t *testing.T) {
+	srv := newReflector(t, "token", []reflectorPrinter{{ID: "A", MachineName: "First"}, {ID: "B", MachineName: "Second"}})
+
+	printers, err := getAccountPrinters(srv.URL+"/", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(printers) != 2 || printers[0].ID != "A" || printers[1].MachineName != "Second" {
+		t.Errorf("unexpected printers %+v", printers)
+	}
+
+	_, err = getAccountPrinters(srv.URL, "wrong")
+	if err == nil {
+		t.Error("expected an error for a rejected token")
+	}
+}
+
+func TestAddAccountPrinters(t *testing.T) {
+	fakes := useFakeBackend(t, connectionTypeRemote)
+
+	srv := newReflector(t, "token", []reflectorPrinter{{ID: "A"}, {ID: "B"}, {ID: ""}})
+
+	ctx := newTestContext(t, &config{ThingiverseToken: "token", ReflectorBaseURL: srv.URL})
+	ctx.Printers.Add(newPrinterConnection(ctx, printerConfig{ConnectionType: connectionTypeRemote, ID: "A"}))
+
+	err := ctx.addAccountPrinters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Adding again must not duplicate anything
+	err = ctx.addAccountPrinters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.Printers.RLock()
+	count := len(ctx.Printers.list)
+	ctx.Printers.RUnlock()
+
+	if count != 2 {
+		t.Fatalf("have %d printers, want 2", count)
+	}
+
+	b, ok := ctx.Printers.Find("B")
+	if !ok {
+		t.Fatal("printer B was not added")
+	}
+
+	if b.config.ConnectionType != connectionTypeRemote {
+		t.Errorf("printer B was added as %q, want remote", b.config.ConnectionType)
+	}
+
+	waitFor(t, func() bool { return len(fakes.Created()) == 1 })
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+// Camera frame formats as reported in makerbot.CameraFrameMetadata.Format
+const (
+	cameraFrameFormatYUYV = 1
+	cameraFrameFormatJPEG = 2
+)
+
+// printerBackend is everything makerbotd needs from a connection to a printer.
+// The MakerBot printer model is used as the common model, so backends for other
+// kinds of printers translate their state into it.
+type printerBackend interface {
+	// Connect connects and authenticates to the printer
+	Connect() error
+	// Close disconnects from the printer. The disconnect handler is called afterwards.
+	Close() error
+
+	// Printer returns the printer's info and latest state. It is only valid after Connect succeeds.
+	Printer() *makerbot.Printer
+
+	HandleDisconnect(func())
+	HandleStateChange(func(old, new *makerbot.PrinterMetadata))
+
+	GetCameraFrame() (*makerbot.CameraFrame, error)
+	Print(filename string, data io.Reader, size int) error
+
+	Suspend() error
+	Resume() error
+	Cancel() error
+	ProcessMethod(method string) error
+
+	LoadFilament(toolIndex int) error
+	UnloadFilament(toolIndex int) error
+
+	// Ping makes a cheap request to the printer to check that it is still
+	// answering. Backends that can't do that return errPingUnsupported.
+	Ping() error
+}
+
+// rpcBackend is implemented by backends that can pass arbitrary JSON-RPC calls
+// straight through to the printer
+type rpcBackend interface {
+	Call(method string, params json.RawMessage) (json.RawMessage, error)
+}
+
+var (
+	errRawRPCUnsupported = errors.New("this printer's backend does not support raw RPC calls")
+	errPingUnsupported   = errors.New("this printer's backend can't be pinged")
+)
+
+// printerBackends maps a printerConfig.ConnectionType to a constructor for its backend
+var printerBackends = map[string]func(ctx *mbContext, conf printerConfig) printerBackend{
+	connectionTypeLocal:     newMakerbotBackend,
+	connectionTypeRemote:    newMakerbotBackend,
+	connectionTypeMoonraker: newMoonrakerBackend,
+}
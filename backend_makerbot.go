@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+// makerbotBackend talks to a MakerBot printer, either directly on the local
+// network or through MakerBot Reflector
+type makerbotBackend struct {
+	context *mbContext
+	config  printerConfig
+	client  *makerbot.Client
+	rpc     *makerbotRPC // rpc is our own connection for raw calls. It is nil for remote printers.
+}
+
+// rawDataMethods are followed by raw bytes on the stream, which a raw call can't
+// pass along
+var rawDataMethods = map[string]bool{
+	"put_raw":              true,
+	"request_camera_frame": true,
+}
+
+func newMakerbotBackend(ctx *mbContext, conf printerConfig) printerBackend {
+	cl := makerbot.NewClient()
+	cl.Timeout = 10 * time.Second
+
+	b := &makerbotBackend{context: ctx, config: conf, client: &cl}
+
+	if conf.ConnectionType == connectionTypeLocal {
+		b.rpc = newMakerbotRPC(conf.IP, conf.Port, conf.AuthPort, ctx.Config.ThingiverseUsername, ctx.Config.ThingiverseToken, cl.Timeout)
+	}
+
+	return b
+}
+
+func (b *makerbotBackend) connectLocal() error {
+	b.context.Debugf("makerbotBackend: connecting local (%s, %s)...\n", b.config.IP, b.config.Port)
+
+	err := b.client.ConnectLocal(b.config.IP, b.config.Port)
+	if err != nil {
+		return err
+	}
+
+	b.context.Debugf("makerbotBackend: connected, authenticating (%s, %s)...\n", b.config.IP, b.config.Port)
+
+	return b.client.AuthenticateWithThingiverse(b.context.Config.ThingiverseToken, b.context.Config.ThingiverseUsername)
+}
+
+func (b *makerbotBackend) connectRemote() error {
+	b.context.Debugln("makerbotBackend: connecting remote...")
+
+	return b.client.ConnectRemote(b.config.ID, b.context.Config.ThingiverseToken)
+}
+
+// Connect implements printerBackend.Connect
+func (b *makerbotBackend) Connect() error {
+	if b.config.ConnectionType == connectionTypeRemote {
+		return b.connectRemote()
+	}
+
+	return b.connectLocal()
+}
+
+// Close implements printerBackend.Close
+func (b *makerbotBackend) Close() error {
+	if b.rpc != nil {
+		b.rpc.Close()
+	}
+
+	return b.client.Close()
+}
+
+// Printer implements printerBackend.Printer
+func (b *makerbotBackend) Printer() *makerbot.Printer {
+	return b.client.Printer
+}
+
+// HandleDisconnect implements printerBackend.HandleDisconnect
+func (b *makerbotBackend) HandleDisconnect(h func()) {
+	b.client.HandleDisconnect(h)
+}
+
+// HandleStateChange implements printerBackend.HandleStateChange
+func (b *makerbotBackend) HandleStateChange(h func(old, new *makerbot.PrinterMetadata)) {
+	b.client.HandleStateChange(h)
+}
+
+// GetCameraFrame implements printerBackend.GetCameraFrame
+func (b *makerbotBackend) GetCameraFrame() (*makerbot.CameraFrame, error) {
+	return b.client.GetCameraFrame()
+}
+
+// Print implements printerBackend.Print
+func (b *makerbotBackend) Print(filename string, data io.Reader, size int) error {
+	return b.client.Print(filename, data, size)
+}
+
+// Suspend implements printerBackend.Suspend
+func (b *makerbotBackend) Suspend() error {
+	_, err := b.client.Suspend()
+	return err
+}
+
+// Resume implements printerBackend.Resume
+func (b *makerbotBackend) Resume() error {
+	_, err := b.client.Resume()
+	return err
+}
+
+// Cancel implements printerBackend.Cancel
+func (b *makerbotBackend) Cancel() error {
+	_, err := b.client.Cancel()
+	return err
+}
+
+// ProcessMethod implements printerBackend.ProcessMethod
+func (b *makerbotBackend) ProcessMethod(method string) error {
+	_, err := b.client.ProcessMethod(method)
+	return err
+}
+
+// LoadFilament implements printerBackend.LoadFilament
+func (b *makerbotBackend) LoadFilament(toolIndex int) error {
+	_, err := b.client.LoadFilament(toolIndex)
+	return err
+}
+
+// UnloadFilament implements printerBackend.UnloadFilament
+func (b *makerbotBackend) UnloadFilament(toolIndex int) error {
+	_, err := b.client.UnloadFilament(toolIndex)
+	return err
+}
+
+// Ping implements printerBackend.Ping. makerbot-rpc has no request of its own
+// that is cheap enough to ping with, and pinging over our raw connection would
+// say nothing about the connection state comes in on. MakerBot printers keep
+// sending their state, temperatures included, while they're connected though, so the heartbeat notices a
+// half-open connection by them going quiet.
+func (b *makerbotBackend) Ping() error {
+	return errPingUnsupported
+}
+
+// Call implements rpcBackend.Call. makerbot.Client only exposes its own calls,
+// not a way to send arbitrary ones, so calls go over a JSON-RPC connection of
+// our own. We can only open one to printers on the local network: remote
+// printers are reached through MakerBot's relay on the client's connection, so
+// they don't support raw calls.
+func (b *makerbotBackend) Call(method string, params json.RawMessage) (json.RawMessage, error) {
+	if b.rpc == nil {
+		return nil, errRawRPCUnsupported
+	}
+
+	if rawDataMethods[method] {
+		return nil, fmt.Errorf("%s can't be called directly", method)
+	}
+
+	return b.rpc.Call(method, params)
+}
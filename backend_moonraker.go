@@ -0,0 +1,534 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // snapshots are usually JPEG
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+const (
+	connectionTypeMoonraker = "moonraker"
+
+	defaultMoonrakerPort = "7125"
+
+	// moonrakerPollInterval is how often printer state is fetched from Moonraker
+	moonrakerPollInterval = 2 * time.Second
+	// moonrakerMaxFailures is how many polls in a row can fail before we consider
+	// the printer disconnected
+	moonrakerMaxFailures = 3
+
+	// moonrakerRequestTimeout is how long a quick request like a status query can take
+	moonrakerRequestTimeout = 10 * time.Second
+	// moonrakerGcodeTimeout is how long a G-code script can take. Moonraker only
+	// answers once the script is done, and LOAD_FILAMENT has to heat the nozzle first.
+	moonrakerGcodeTimeout = 10 * time.Minute
+)
+
+type moonrakerResult struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type moonrakerStatus struct {
+	PrintStats struct {
+		State         string  `json:"state"`
+		Filename      string  `json:"filename"`
+		PrintDuration float64 `json:"print_duration"`
+		Message       string  `json:"message"`
+	} `json:"print_stats"`
+	DisplayStatus struct {
+		Progress float64 `json:"progress"`
+	} `json:"display_status"`
+	Extruder struct {
+		Temperature float64 `json:"temperature"`
+		Target      float64 `json:"target"`
+	} `json:"extruder"`
+}
+
+// moonrakerBackend talks to a Klipper printer through Moonraker's HTTP API and
+// translates its state into the MakerBot printer model
+type moonrakerBackend struct {
+	sync.Mutex
+	context *mbContext
+	config  printerConfig
+	base    string
+	http    *http.Client
+
+	printer      *makerbot.Printer
+	done         chan struct{}
+	onDisconnect func()
+	onState      func(old, new *makerbot.PrinterMetadata)
+	jobID        int
+}
+
+func newMoonrakerBackend(ctx *mbContext, conf printerConfig) printerBackend {
+	port := conf.Port
+	if port == "" {
+		port = defaultMoonrakerPort
+	}
+
+	return &moonrakerBackend{
+		context: ctx,
+		config:  conf,
+		base:    "http://" + net.JoinHostPort(conf.IP, port),
+		http:    &http.Client{},
+		done:    make(chan struct{}),
+	}
+}
+
+func (b *moonrakerBackend) request(method, endpoint string, body io.Reader, contentType string, result interface{}) error {
+	return b.requestTimeout(moonrakerRequestTimeout, method, endpoint, body, contentType, result)
+}
+
+// requestTimeout sends a request to Moonraker and gives up on it after `timeout`,
+// or only once the backend is closed if `timeout` is 0
+func (b *moonrakerBackend) requestTimeout(timeout time.Duration, method, endpoint string, body io.Reader, contentType string, result interface{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	}
+	defer cancel()
+
+	go func() {
+		select {
+		case <-b.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, method, b.base+endpoint, body)
+	if err != nil {
+		return err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if b.config.APIKey != "" {
+		req.Header.Set("X-Api-Key", b.config.APIKey)
+	}
+
+	res, err := b.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var mr moonrakerResult
+	err = json.NewDecoder(res.Body).Decode(&mr)
+	if err != nil {
+		return fmt.Errorf("moonraker returned %s", res.Status)
+	}
+
+	if mr.Error != nil {
+		return errors.New(mr.Error.Message)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(mr.Result, result)
+}
+
+func (b *moonrakerBackend) status() (*moonrakerStatus, error) {
+	var res struct {
+		Status moonrakerStatus `json:"status"`
+	}
+
+	err := b.request("GET", "/printer/objects/query?print_stats&display_status&extruder", nil, "", &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Status, nil
+}
+
+// metadata translates Moonraker's status into the MakerBot model. `prev` is used
+// to keep a stable process ID for the same job.
+func (b *moonrakerBackend) metadata(st *moonrakerStatus, prev *makerbot.PrinterMetadata) *makerbot.PrinterMetadata {
+	md := &makerbot.PrinterMetadata{MachineName: b.printer.MachineName}
+	md.Toolheads.Extruder = []makerbot.ToolheadMetadata{{
+		Index:              0,
+		ToolPresent:        true,
+		CurrentTemperature: int(st.Extruder.Temperature),
+		TargetTemperature:  int(st.Extruder.Target),
+	}}
+
+	ps := st.PrintStats
+	if ps.State == "" || ps.State == "standby" {
+		return md
+	}
+
+	proc := &makerbot.PrinterProcess{
+		Name:        "PrintProcess",
+		Progress:    int(st.DisplayStatus.Progress * 100),
+		ElapsedTime: int(ps.PrintDuration),
+		Filename:    ps.Filename,
+		FilePath:    ps.Filename,
+	}
+
+	switch ps.State {
+	case "printing":
+		proc.Step = "printing"
+		proc.Cancellable = true
+		proc.Methods = []string{"suspend"}
+	case "paused":
+		proc.Step = "suspended"
+		proc.Cancellable = true
+		proc.Methods = []string{"resume"}
+	case "complete":
+		proc.Step = "completed"
+		proc.Complete = true
+		proc.Progress = 100
+	case "cancelled":
+		proc.Step = "cancelled"
+		proc.Complete = true
+		proc.Cancelled = true
+	default:
+		proc.Step = "failed"
+		proc.Complete = true
+		if ps.Message != "" {
+			reason := ps.Message
+			proc.Reason = &reason
+		}
+	}
+
+	if prev != nil && prev.CurrentProcess != nil && prev.CurrentProcess.Filename == ps.Filename && !(prev.CurrentProcess.Complete && !proc.Complete) {
+		proc.ID = prev.CurrentProcess.ID
+	} else {
+		b.jobID++
+		proc.ID = b.jobID
+	}
+
+	md.CurrentProcess = proc
+	return md
+}
+
+// poll keeps the printer state up to date until the backend is closed or
+// Moonraker stops answering
+func (b *moonrakerBackend) poll() {
+	ticker := time.NewTicker(moonrakerPollInterval)
+	defer ticker.Stop()
+
+	failures := 0
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+		}
+
+		st, err := b.status()
+		if err != nil {
+			failures++
+			b.context.Debugf("moonrakerBackend: poll failed (%d/%d): %v\n", failures, moonrakerMaxFailures, err)
+
+			if failures >= moonrakerMaxFailures {
+				b.Close()
+				return
+			}
+
+			continue
+		}
+		failures = 0
+
+		b.updateStatus(st)
+	}
+}
+
+func (b *moonrakerBackend) updateStatus(st *moonrakerStatus) {
+	b.Lock()
+	old := b.printer.Metadata
+	np := *b.printer
+	np.Metadata = b.metadata(st, old)
+	b.printer = &np
+	onState := b.onState
+	b.Unlock()
+
+	if onState != nil {
+		onState(old, np.Metadata)
+	}
+}
+
+// Connect implements printerBackend.Connect
+func (b *moonrakerBackend) Connect() error {
+	b.context.Debugf("moonrakerBackend: connecting (%s)...\n", b.base)
+
+	var info struct {
+		State           string `json:"state"`
+		StateMessage    string `json:"state_message"`
+		Hostname        string `json:"hostname"`
+		SoftwareVersion string `json:"software_version"`
+	}
+
+	err := b.request("GET", "/printer/info", nil, "", &info)
+	if err != nil {
+		return err
+	}
+
+	if info.State != "ready" {
+		return fmt.Errorf("klipper is not ready (%s): %s", info.State, info.StateMessage)
+	}
+
+	serial := b.config.ID
+	if serial == "" {
+		serial = info.Hostname
+	}
+
+	u, _ := url.Parse(b.base)
+
+	b.printer = &makerbot.Printer{
+		MachineType: "klipper",
+		BotType:     connectionTypeMoonraker,
+		MachineName: info.Hostname,
+		Serial:      serial,
+		APIVersion:  info.SoftwareVersion,
+		IP:          u.Hostname(),
+		Port:        u.Port(),
+		Metadata:    &makerbot.PrinterMetadata{MachineName: info.Hostname},
+	}
+
+	st, err := b.status()
+	if err != nil {
+		return err
+	}
+	b.updateStatus(st)
+
+	go b.poll()
+
+	return nil
+}
+
+// Close implements printerBackend.Close
+func (b *moonrakerBackend) Close() error {
+	b.Lock()
+	select {
+	case <-b.done:
+		b.Unlock()
+		return nil
+	default:
+	}
+
+	close(b.done)
+	onDisconnect := b.onDisconnect
+	b.Unlock()
+
+	if onDisconnect != nil {
+		onDisconnect()
+	}
+
+	return nil
+}
+
+// Printer implements printerBackend.Printer
+func (b *moonrakerBackend) Printer() *makerbot.Printer {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.printer
+}
+
+// HandleDisconnect implements printerBackend.HandleDisconnect
+func (b *moonrakerBackend) HandleDisconnect(h func()) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.onDisconnect = h
+}
+
+// HandleStateChange implements printerBackend.HandleStateChange
+func (b *moonrakerBackend) HandleStateChange(h func(old, new *makerbot.PrinterMetadata)) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.onState = h
+}
+
+// GetCameraFrame implements printerBackend.GetCameraFrame
+func (b *moonrakerBackend) GetCameraFrame() (*makerbot.CameraFrame, error) {
+	snapshot := "/webcam/?action=snapshot"
+
+	var cams struct {
+		Webcams []struct {
+			Enabled     bool   `json:"enabled"`
+			SnapshotURL string `json:"snapshot_url"`
+		} `json:"webcams"`
+	}
+
+	err := b.request("GET", "/server/webcams/list", nil, "", &cams)
+	if err == nil {
+		for _, c := range cams.Webcams {
+			if c.Enabled && c.SnapshotURL != "" {
+				snapshot = c.SnapshotURL
+				break
+			}
+		}
+	}
+
+	if !strings.HasPrefix(snapshot, "http://") && !strings.HasPrefix(snapshot, "https://") {
+		// Relative snapshot URLs are served by the web server in front of Moonraker
+		u, _ := url.Parse(b.base)
+		snapshot = "http://" + u.Hostname() + "/" + strings.TrimPrefix(snapshot, "/")
+	}
+
+	res, err := b.http.Get(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("webcam returned %s", res.Status)
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &makerbot.CameraFrameMetadata{FileSize: uint32(len(data)), Format: cameraFrameFormatJPEG}
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		meta.Width = uint32(cfg.Width)
+		meta.Height = uint32(cfg.Height)
+	}
+
+	return &makerbot.CameraFrame{Data: data, Metadata: meta}, nil
+}
+
+// Print implements printerBackend.Print. The file is uploaded to Moonraker's
+// gcodes root and started right away. Large files can take a while to upload,
+// so the upload has no timeout and is only given up on if the printer goes away.
+func (b *moonrakerBackend) Print(filename string, data io.Reader, size int) error {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	go func() {
+		err := writer.WriteField("print", "true")
+		if err == nil {
+			var part io.Writer
+			part, err = writer.CreateFormFile("file", filename)
+			if err == nil {
+				_, err = io.Copy(part, data)
+			}
+		}
+
+		if err == nil {
+			err = writer.Close()
+		}
+
+		pw.CloseWithError(err)
+	}()
+
+	return b.requestTimeout(0, "POST", "/server/files/upload", pr, writer.FormDataContentType(), nil)
+}
+
+func (b *moonrakerBackend) post(endpoint string) error {
+	return b.request("POST", endpoint, nil, "", nil)
+}
+
+// Suspend implements printerBackend.Suspend
+func (b *moonrakerBackend) Suspend() error {
+	return b.post("/printer/print/pause")
+}
+
+// Resume implements printerBackend.Resume
+func (b *moonrakerBackend) Resume() error {
+	return b.post("/printer/print/resume")
+}
+
+// Cancel implements printerBackend.Cancel
+func (b *moonrakerBackend) Cancel() error {
+	return b.post("/printer/print/cancel")
+}
+
+// ProcessMethod implements printerBackend.ProcessMethod
+func (b *moonrakerBackend) ProcessMethod(method string) error {
+	switch method {
+	case "suspend":
+		return b.Suspend()
+	case "resume":
+		return b.Resume()
+	}
+
+	return fmt.Errorf("process method %s is not supported by moonraker", method)
+}
+
+// gcode runs a G-code script on the printer
+func (b *moonrakerBackend) gcode(script string) error {
+	return b.requestTimeout(moonrakerGcodeTimeout, "POST", "/printer/gcode/script?script="+url.QueryEscape(script), nil, "", nil)
+}
+
+// toolChange selects the tool on printers with more than one extruder. Single
+// extruder configs usually don't define T0.
+func toolChange(toolIndex int) string {
+	if toolIndex == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("T%d\n", toolIndex)
+}
+
+// LoadFilament implements printerBackend.LoadFilament using the LOAD_FILAMENT
+// macro most Klipper configs define
+func (b *moonrakerBackend) LoadFilament(toolIndex int) error {
+	return b.gcode(toolChange(toolIndex) + "LOAD_FILAMENT")
+}
+
+// UnloadFilament implements printerBackend.UnloadFilament using the
+// UNLOAD_FILAMENT macro most Klipper configs define
+func (b *moonrakerBackend) UnloadFilament(toolIndex int) error {
+	return b.gcode(toolChange(toolIndex) + "UNLOAD_FILAMENT")
+}
+
+// Ping implements printerBackend.Ping
+func (b *moonrakerBackend) Ping() error {
+	return b.request("GET", "/server/info", nil, "", nil)
+}
+
+// Call implements rpcBackend.Call using Moonraker's JSON-RPC over HTTP endpoint.
+// Methods like printer.gcode.script only answer once they're done, so calls get
+// as long as G-code scripts.
+func (b *moonrakerBackend) Call(method string, params json.RawMessage) (json.RawMessage, error) {
+	req := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+	}
+
+	if len(params) > 0 {
+		req["params"] = params
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result json.RawMessage
+	err = b.requestTimeout(moonrakerGcodeTimeout, "POST", "/server/jsonrpc", bytes.NewReader(body), "application/json", &result)
+
+	return result, err
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+// fakeMoonraker serves enough of Moonraker's API to drive a moonrakerBackend
+type fakeMoonraker struct {
+	sync.Mutex
+	apiKey   string
+	state    string
+	progress float64
+	filename string
+	files    map[string][]byte
+	posts    []string
+	gcode    []string
+
+	// hold, if set, is sent to when a G-code script comes in, and the script
+	// then never finishes
+	hold chan struct{}
+}
+
+func (fm *fakeMoonraker) reply(w http.ResponseWriter, result interface{}) {
+	json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
+}
+
+func (fm *fakeMoonraker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if fm.hold != nil && r.URL.Path == "/printer/gcode/script" {
+		fm.hold <- struct{}{}
+		<-r.Context().Done()
+		return
+	}
+
+	fm.Lock()
+	defer fm.Unlock()
+
+	// The webcam is served by its own streamer, which doesn't check the API key
+	if r.URL.Path != "/snapshot" && fm.apiKey != "" && r.Header.Get("X-Api-Key") != fm.apiKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"code": 401, "message": "Unauthorized"}})
+		return
+	}
+
+	switch r.URL.Path {
+	case "/printer/info":
+		fm.reply(w, map[string]string{"state": "ready", "hostname": "voron", "software_version": "v0.12.0"})
+
+	case "/printer/objects/query":
+		fm.reply(w, map[string]interface{}{"status": map[string]interface{}{
+			"print_stats":    map[string]interface{}{"state": fm.state, "filename": fm.filename, "print_duration": 42.0},
+			"display_status": map[string]interface{}{"progress": fm.progress},
+			"extruder":       map[string]interface{}{"temperature": 210.4, "target": 215.0},
+		}})
+
+	case "/server/files/upload":
+		file, header, err := r.FormFile("file")
+		if err != nil || r.FormValue("print") != "true" {
+			http.Error(w, "bad upload", http.StatusBadRequest)
+			return
+		}
+
+		data, _ := ioutil.ReadAll(file)
+		fm.files[header.Filename] = data
+		fm.state, fm.filename = "printing", header.Filename
+		fm.reply(w, map[string]interface{}{"item": map[string]string{"path": header.Filename}})
+
+	case "/printer/gcode/script":
+		fm.gcode = append(fm.gcode, r.URL.Query().Get("script"))
+		fm.reply(w, "ok")
+
+	case "/server/webcams/list":
+		fm.reply(w, map[string]interface{}{"webcams": []map[string]interface{}{
+			{"enabled": true, "snapshot_url": "http://" + r.Host + "/snapshot"},
+		}})
+
+	case "/snapshot":
+		png.Encode(w, image.NewRGBA(image.Rect(0, 0, 4, 3)))
+
+	case "/server/jsonrpc":
+		var req struct {
+			Method string `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		fm.reply(w, map[string]string{"called": req.Method})
+
+	default:
+		fm.posts = append(fm.posts, r.URL.Path)
+		fm.reply(w, "ok")
+	}
+}
+
+func startFakeMoonraker(t *testing.T, fm *fakeMoonraker) printerConfig {
+	fm.files = map[string][]byte{}
+
+	srv := httptest.NewServer(fm)
+	t.Cleanup(srv.Close)
+
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	return printerConfig{ConnectionType: connectionTypeMoonraker, IP: host, Port: port, APIKey: fm.apiKey}
+}
+
+func TestMoonrakerBackend(t *testing.T) {
+	fm := &fakeMoonraker{apiKey: "secret", state: "standby"}
+	conf := startFakeMoonraker(t, fm)
+	ctx := newTestContext(t, &config{})
+
+	pc := newPrinterConnection(ctx, conf)
+	ctx.Printers.Add(pc)
+
+	err := pc.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := pc.connection.(*moonrakerBackend)
+
+	p := b.Printer()
+	if p.Serial != "voron" || p.MachineType != "klipper" || p.Metadata.CurrentProcess != nil {
+		t.Errorf("unexpected printer %+v", p)
+	}
+
+	if temp := p.Metadata.Toolheads.Extruder[0]; temp.CurrentTemperature != 210 || temp.TargetTemperature != 215 {
+		t.Errorf("unexpected extruder %+v", temp)
+	}
+
+	if found, ok := ctx.Printers.Find("voron"); !ok || found != pc {
+		t.Error("printer can't be found by its hostname")
+	}
+
+	file := bytes.Repeat([]byte("G1 X0\n"), 100)
+	err = b.Print("part.gcode", bytes.NewReader(file), len(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fm.Lock()
+	uploaded := fm.files["part.gcode"]
+	fm.progress = 0.5
+	fm.Unlock()
+
+	if !bytes.Equal(uploaded, file) {
+		t.Errorf("moonraker received %d bytes, want %d", len(uploaded), len(file))
+	}
+
+	st, err := b.status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.updateStatus(st)
+
+	proc := b.Printer().Metadata.CurrentProcess
+	if proc == nil || proc.Step != "printing" || proc.Progress != 50 || proc.Filename != "part.gcode" || proc.ElapsedTime != 42 {
+		t.Fatalf("unexpected process %+v", proc)
+	}
+
+	if pc.JobProgress() == nil {
+		t.Error("the connection did not pick up the job")
+	}
+
+	for _, method := range []func() error{b.Suspend, b.Resume, b.Cancel} {
+		err = method()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = b.LoadFilament(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fm.Lock()
+	posts, gcode := fm.posts, fm.gcode
+	fm.Unlock()
+
+	if len(posts) != 3 || posts[0] != "/printer/print/pause" || posts[1] != "/printer/print/resume" || posts[2] != "/printer/print/cancel" {
+		t.Errorf("unexpected requests %v", posts)
+	}
+
+	if len(gcode) != 1 || gcode[0] != "T1\nLOAD_FILAMENT" {
+		t.Errorf("unexpected G-code %q", gcode)
+	}
+
+	frame, err := b.GetCameraFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if frame.Metadata.Width != 4 || frame.Metadata.Height != 3 {
+		t.Errorf("unexpected frame metadata %+v", frame.Metadata)
+	}
+
+	result, err := b.Call("server.info", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(bytes.TrimSpace(result)) != `{"called":"server.info"}` {
+		t.Errorf("unexpected RPC result %s", result)
+	}
+
+	err = b.Ping()
+	if err != nil {
+		t.Errorf("Ping() = %v", err)
+	}
+}
+
+func TestMoonrakerMetadata(t *testing.T) {
+	b := &moonrakerBackend{printer: &makerbot.Printer{MachineName: "voron"}}
+
+	tests := []struct {
+		state    string
+		step     string
+		complete bool
+		methods  []string
+	}{
+		{"printing", "printing", false, []string{"suspend"}},
+		{"paused", "suspended", false, []string{"resume"}},
+		{"complete", "completed", true, nil},
+		{"cancelled", "cancelled", true, nil},
+		{"error", "failed", true, nil},
+	}
+
+	var prev *makerbot.PrinterMetadata
+	for _, test := range tests {
+		st := &moonrakerStatus{}
+		st.PrintStats.State = test.state
+		st.PrintStats.Filename = "part.gcode"
+		st.PrintStats.Message = "thermal runaway"
+
+		md := b.metadata(st, prev)
+		proc := md.CurrentProcess
+		if proc == nil {
+			t.Fatalf("%s: no process", test.state)
+		}
+
+		if proc.Step != test.step || proc.Complete != test.complete || len(proc.Methods) != len(test.methods) {
+			t.Errorf("%s: unexpected process %+v", test.state, proc)
+		}
+
+		if proc.ID != 1 {
+			t.Errorf("%s: the same job got a new process ID %d", test.state, proc.ID)
+		}
+
+		if test.state == "error" && (proc.Reason == nil || *proc.Reason != "thermal runaway") {
+			t.Errorf("error: reason = %v", proc.Reason)
+		}
+
+		prev = md
+	}
+
+	st := &moonrakerStatus{}
+	st.PrintStats.State = "printing"
+	st.PrintStats.Filename = "part.gcode"
+
+	if md := b.metadata(st, prev); md.CurrentProcess.ID != 2 {
+		t.Errorf("restarting a finished job kept process ID %d", md.CurrentProcess.ID)
+	}
+
+	st.PrintStats.State = "standby"
+	if md := b.metadata(st, prev); md.CurrentProcess != nil {
+		t.Errorf("standby has a process: %+v", md.CurrentProcess)
+	}
+}
+
+func TestMoonrakerAPIKey(t *testing.T) {
+	fm := &fakeMoonraker{apiKey: "secret", state: "standby"}
+	conf := startFakeMoonraker(t, fm)
+	conf.APIKey = "wrong"
+
+	b := newMoonrakerBackend(newTestContext(t, &config{}), conf)
+
+	err := b.Connect()
+	if err == nil || err.Error() != "Unauthorized" {
+		t.Errorf("Connect() = %v, want Moonraker's error", err)
+	}
+}
+
+func TestMoonrakerCloseCancelsRequests(t *testing.T) {
+	fm := &fakeMoonraker{state: "standby", hold: make(chan struct{})}
+	conf := startFakeMoonraker(t, fm)
+
+	b := newMoonrakerBackend(newTestContext(t, &config{}), conf)
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error, 1)
+	go func() { errs <- b.LoadFilament(0) }()
+
+	<-fm.hold
+	b.Close()
+
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("a G-code script that never finished succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("closing the backend didn't cancel a running G-code script")
+	}
+}
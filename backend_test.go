@@ -0,0 +1,265 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+// fakeBackend is a printerBackend that keeps its printer's state in memory
+type fakeBackend struct {
+	mu            sync.Mutex
+	config        printerConfig
+	printer       *makerbot.Printer
+	onDisconnect  func()
+	onStateChange func(old, new *makerbot.PrinterMetadata)
+	closed        bool
+	pingErr       error                 // pingErr is what Ping returns
+	pings         int                   // pings counts calls to Ping
+	printed       map[string][]byte     // printed is every print file received, by name
+	hold          chan struct{}         // hold, if set, makes commands wait until it is closed
+	methods       []string              // methods is every process method called
+	frame         *makerbot.CameraFrame // frame, if set, is what GetCameraFrame returns
+	frames        int                   // frames counts calls to GetCameraFrame
+}
+
+func newFakeBackend(conf printerConfig) *fakeBackend {
+	return &fakeBackend{
+		config: conf,
+		printer: &makerbot.Printer{
+			Serial:      "FAKE" + conf.ID,
+			MachineName: "Fake " + conf.ID,
+			IP:          conf.IP,
+			Port:        conf.Port,
+			Metadata:    &makerbot.PrinterMetadata{},
+		},
+		printed: map[string][]byte{},
+	}
+}
+
+// fakeBackends swaps the backend of a connection type for fakeBackends for
+// the rest of the test, and records every one that gets created
+type fakeBackends struct {
+	sync.Mutex
+	created []*fakeBackend
+}
+
+func useFakeBackend(t *testing.T, connectionType string) *fakeBackends {
+	fakes := &fakeBackends{}
+
+	orig := printerBackends[connectionType]
+	printerBackends[connectionType] = func(_ *mbContext, conf printerConfig) printerBackend {
+		fb := newFakeBackend(conf)
+
+		fakes.Lock()
+		fakes.created = append(fakes.created, fb)
+		fakes.Unlock()
+
+		return fb
+	}
+	t.Cleanup(func() { printerBackends[connectionType] = orig })
+
+	return fakes
+}
+
+func (fakes *fakeBackends) Created() []*fakeBackend {
+	fakes.Lock()
+	defer fakes.Unlock()
+
+	return append([]*fakeBackend{}, fakes.created...)
+}
+
+// newTestContext returns an mbContext with no printers, which shuts down when the test ends
+func newTestContext(t *testing.T, conf *config) *mbContext {
+	spools, err := loadSpoolInventory(filepath.Join(t.TempDir(), "spools.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := &mbContext{
+		Config:     conf,
+		Printers:   &printerConnections{},
+		Spools:     spools,
+		Operations: newOperations(),
+		Metrics:    newMetrics(),
+		done:       make(chan struct{}),
+	}
+	t.Cleanup(func() {
+		if !ctx.ShuttingDown() {
+			close(ctx.done)
+		}
+	})
+
+	return ctx
+}
+
+// addFakePrinter adds a printer with a connected fakeBackend to ctx
+func addFakePrinter(t *testing.T, ctx *mbContext, conf printerConfig) (*printerConnection, *fakeBackend) {
+	if conf.ConnectionType == "" {
+		conf.ConnectionType = connectionTypeLocal
+	}
+
+	fakes := useFakeBackend(t, conf.ConnectionType)
+
+	pc := newPrinterConnection(ctx, conf)
+	ctx.Printers.Add(pc)
+
+	err := pc.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := fakes.Created()
+	return pc, created[len(created)-1]
+}
+
+// waitFor fails the test if `cond` doesn't become true within a few seconds
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// setState replaces the printer's state and lets the connection know it changed
+func (b *fakeBackend) setState(update func(m *makerbot.PrinterMetadata)) {
+	b.mu.Lock()
+	old := b.printer.Metadata
+	next := *old
+	update(&next)
+
+	p := *b.printer
+	p.Metadata = &next
+	b.printer = &p
+	h := b.onStateChange
+	b.mu.Unlock()
+
+	if h != nil {
+		h(old, &next)
+	}
+}
+
+func (b *fakeBackend) Connect() error { return nil }
+
+func (b *fakeBackend) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	h := b.onDisconnect
+	b.mu.Unlock()
+
+	// Like makerbot-rpc, the disconnect is handled on another goroutine
+	if h != nil {
+		go h()
+	}
+
+	return nil
+}
+
+func (b *fakeBackend) Printer() *makerbot.Printer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.printer
+}
+
+func (b *fakeBackend) HandleDisconnect(h func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.onDisconnect = h
+}
+
+func (b *fakeBackend) HandleStateChange(h func(old, new *makerbot.PrinterMetadata)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.onStateChange = h
+}
+
+func (b *fakeBackend) GetCameraFrame() (*makerbot.CameraFrame, error) {
+	b.mu.Lock()
+	b.frames++
+	frame := b.frame
+	b.mu.Unlock()
+
+	b.wait()
+
+	if frame == nil {
+		return nil, errors.New("fake printers have no camera")
+	}
+
+	return frame, nil
+}
+
+// wait blocks until hold is closed, if it is set
+func (b *fakeBackend) wait() {
+	b.mu.Lock()
+	hold := b.hold
+	b.mu.Unlock()
+
+	if hold != nil {
+		<-hold
+	}
+}
+
+func (b *fakeBackend) Print(filename string, data io.Reader, size int) error {
+	b.wait()
+
+	body, err := ioutil.ReadAll(data)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.printed[filename] = body
+	b.mu.Unlock()
+
+	b.setState(func(m *makerbot.PrinterMetadata) {
+		m.CurrentProcess = &makerbot.PrinterProcess{ID: 1, Name: "PrintProcess", Step: "printing", Filename: filename}
+	})
+
+	return nil
+}
+
+// Printed returns the print file received as `filename`
+func (b *fakeBackend) Printed(filename string) ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	body, ok := b.printed[filename]
+	return body, ok
+}
+
+func (b *fakeBackend) Ping() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pings++
+	return b.pingErr
+}
+
+func (b *fakeBackend) Suspend() error                     { return nil }
+func (b *fakeBackend) Resume() error                      { return nil }
+func (b *fakeBackend) Cancel() error                      { return nil }
+func (b *fakeBackend) LoadFilament(toolIndex int) error   { b.wait(); return nil }
+func (b *fakeBackend) UnloadFilament(toolIndex int) error { return nil }
+
+func (b *fakeBackend) ProcessMethod(method string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.methods = append(b.methods, method)
+	return nil
+}
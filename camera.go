@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"strconv"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+const (
+	snapshotFormatJPEG = "jpeg"
+	snapshotFormatPNG  = "png"
+
+	maxSnapshotWidth = 4096
+)
+
+// snapshotOptions describe how a camera frame should be served
+type snapshotOptions struct {
+	Format  string
+	Width   int // Width scales the image to this many pixels wide, keeping the aspect ratio. 0 keeps the original size.
+	Quality int // Quality is the JPEG quality, 1-100
+	Rotate  int // Rotate is clockwise, in degrees. Must be 0, 90, 180 or 270.
+}
+
+// parseSnapshotOptions reads ?width=, ?quality= and ?rotate= from `q`
+func parseSnapshotOptions(q url.Values, format string) (snapshotOptions, error) {
+	opts := snapshotOptions{Format: format, Quality: jpeg.DefaultQuality}
+
+	ints := map[string]*int{"width": &opts.Width, "quality": &opts.Quality, "rotate": &opts.Rotate}
+	for name, v := range ints {
+		s := q.Get(name)
+		if s == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return opts, fmt.Errorf("%s must be a number", name)
+		}
+		*v = n
+	}
+
+	return validateSnapshotOptions(opts)
+}
+
+// validateSnapshotOptions checks that `opts` are in range and normalizes the rotation
+func validateSnapshotOptions(opts snapshotOptions) (snapshotOptions, error) {
+	if opts.Width < 0 || opts.Width > maxSnapshotWidth {
+		return opts, fmt.Errorf("width must be between 0, for the original size, and %d", maxSnapshotWidth)
+	}
+
+	if opts.Quality < 1 || opts.Quality > 100 {
+		return opts, errors.New("quality must be between 1 and 100")
+	}
+
+	opts.Rotate = ((opts.Rotate % 360) + 360) % 360
+	if opts.Rotate%90 != 0 {
+		return opts, errors.New("rotate must be a multiple of 90")
+	}
+
+	return opts, nil
+}
+
+// yuyvToImage converts a packed YUYV (YUV 4:2:2) frame, which is what some bots'
+// cameras emit, into an image
+func yuyvToImage(data []byte, width, height int) (image.Image, error) {
+	if width <= 0 || height <= 0 || width%2 != 0 || len(data) < width*height*2 {
+		return nil, errors.New("camera frame is not valid YUYV")
+	}
+
+	img := image.NewYCbCr(image.Rect(0, 0, width, height), image.YCbCrSubsampleRatio422)
+
+	for y := 0; y < height; y++ {
+		row := data[y*width*2:]
+		for x := 0; x < width; x += 2 {
+			i := x * 2
+			img.Y[y*img.YStride+x] = row[i]
+			img.Y[y*img.YStride+x+1] = row[i+2]
+			img.Cb[y*img.CStride+x/2] = row[i+1]
+			img.Cr[y*img.CStride+x/2] = row[i+3]
+		}
+	}
+
+	return img, nil
+}
+
+// decodeCameraFrame turns a camera frame into an image, whatever its encoding
+func decodeCameraFrame(frame *makerbot.CameraFrame) (image.Image, error) {
+	if frame.Metadata != nil && frame.Metadata.Format == cameraFrameFormatYUYV {
+		return yuyvToImage(frame.Data, int(frame.Metadata.Width), int(frame.Metadata.Height))
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(frame.Data))
+	return img, err
+}
+
+// scaleImage resizes `img` to `width` pixels wide with nearest-neighbor sampling
+func scaleImage(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	if width == 0 || width == b.Dx() {
+		return img
+	}
+
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		sy := b.Min.Y + y*b.Dy()/height
+		for x := 0; x < width; x++ {
+			out.Set(x, y, img.At(b.Min.X+x*b.Dx()/width, sy))
+		}
+	}
+
+	return out
+}
+
+// rotateImage rotates `img` clockwise by 90, 180 or 270 degrees
+func rotateImage(img image.Image, degrees int) image.Image {
+	if degrees == 0 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	var out *image.RGBA
+	if degrees == 180 {
+		out = image.NewRGBA(image.Rect(0, 0, w, h))
+	} else {
+		out = image.NewRGBA(image.Rect(0, 0, h, w))
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := img.At(b.Min.X+x, b.Min.Y+y)
+
+			switch degrees {
+			case 90:
+				out.Set(h-1-y, x, c)
+			case 180:
+				out.Set(w-1-x, h-1-y, c)
+			case 270:
+				out.Set(y, w-1-x, c)
+			}
+		}
+	}
+
+	return out
+}
+
+// encodeSnapshot converts a camera frame according to `opts`. It returns the
+// encoded image and its content type.
+func encodeSnapshot(frame *makerbot.CameraFrame, opts snapshotOptions) ([]byte, string, error) {
+	isJPEG := frame.Metadata != nil && frame.Metadata.Format == cameraFrameFormatJPEG
+
+	// Nothing to do, so don't pay for decoding and encoding again
+	if isJPEG && opts.Format == snapshotFormatJPEG && opts.Width == 0 && opts.Rotate == 0 && opts.Quality == jpeg.DefaultQuality {
+		return frame.Data, "image/jpeg", nil
+	}
+
+	img, err := decodeCameraFrame(frame)
+	if err != nil {
+		return nil, "", err
+	}
+
+	img = rotateImage(scaleImage(img, opts.Width), opts.Rotate)
+
+	var buf bytes.Buffer
+
+	if opts.Format == snapshotFormatPNG {
+		err = png.Encode(&buf, img)
+		return buf.Bytes(), "image/png", err
+	}
+
+	err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: opts.Quality})
+	return buf.Bytes(), "image/jpeg", err
+}
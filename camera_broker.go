@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+const defaultCameraFrameInterval = time.Second
+
+// cachedFrame is a camera frame along with when it was fetched
+type cachedFrame struct {
+	Frame     *makerbot.CameraFrame
+	FetchedAt time.Time
+	Hash      string // Hash identifies the frame's contents, for use in ETags
+}
+
+// cameraBroker shares camera frames between everyone who wants one, so a
+// printer is asked for a frame at most once per interval no matter how many
+// snapshot requests come in at the same time
+type cameraBroker struct {
+	sync.Mutex
+	latest   *cachedFrame
+	fetching chan struct{} // fetching is closed when the in-flight fetch finishes
+	err      error
+}
+
+// Frame returns the latest frame if it is newer than `interval`. Otherwise a new
+// frame is fetched with `fetch`, or, if another caller is already fetching one,
+// that frame is waited for.
+func (cb *cameraBroker) Frame(interval time.Duration, fetch func() (*makerbot.CameraFrame, error)) (*cachedFrame, error) {
+	cb.Lock()
+
+	if cb.latest != nil && time.Since(cb.latest.FetchedAt) < interval {
+		latest := cb.latest
+		cb.Unlock()
+		return latest, nil
+	}
+
+	if cb.fetching != nil {
+		wait := cb.fetching
+		cb.Unlock()
+
+		<-wait
+
+		cb.Lock()
+		defer cb.Unlock()
+		return cb.latest, cb.err
+	}
+
+	done := make(chan struct{})
+	cb.fetching = done
+	cb.Unlock()
+
+	frame, err := fetch()
+
+	cb.Lock()
+	defer cb.Unlock()
+
+	cb.err = err
+	if err == nil {
+		h := fnv.New64a()
+		h.Write(frame.Data)
+
+		cb.latest = &cachedFrame{
+			Frame:     frame,
+			FetchedAt: time.Now(),
+			Hash:      fmt.Sprintf("%x", h.Sum64()),
+		}
+	}
+
+	cb.fetching = nil
+	close(done)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cb.latest, nil
+}
+
+// CameraFrame gets a camera frame from the printer through its camera broker.
+// Everything that needs a frame should use this instead of calling the backend.
+func (pc *printerConnection) CameraFrame() (*cachedFrame, error) {
+	interval := defaultCameraFrameInterval
+	if pc.context.Config.CameraFrameInterval > 0 {
+		interval = time.Duration(pc.context.Config.CameraFrameInterval) * time.Millisecond
+	}
+
+	conn := pc.backend()
+	if conn == nil {
+		return nil, errPrinterNotConnected
+	}
+
+	return pc.camera.Frame(interval, func() (*makerbot.CameraFrame, error) {
+		start := time.Now()
+		frame, err := conn.GetCameraFrame()
+		pc.context.Metrics.ObserveCameraFetch(pc.ID(), time.Since(start))
+
+		return frame, err
+	})
+}
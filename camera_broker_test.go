@@ -0,0 +1,86 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+func TestCameraFrameDisconnected(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	pc, fb := addFakePrinter(t, ctx, printerConfig{ID: "1"})
+
+	fb.Close()
+	waitFor(t, func() bool { return pc.backend() == nil })
+
+	frame, err := pc.CameraFrame()
+	if err != errPrinterNotConnected || frame != nil {
+		t.Errorf("CameraFrame() = %v, %v, want %v", frame, err, errPrinterNotConnected)
+	}
+}
+
+func TestCameraFrameShared(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	pc, fb := addFakePrinter(t, ctx, printerConfig{ID: "1"})
+
+	hold := make(chan struct{})
+
+	fb.mu.Lock()
+	fb.frame = &makerbot.CameraFrame{Data: []byte("frame")}
+	fb.hold = hold
+	fb.mu.Unlock()
+
+	fetches := func() int {
+		fb.mu.Lock()
+		defer fb.mu.Unlock()
+
+		return fb.frames
+	}
+
+	var wg sync.WaitGroup
+	hashes := make([]string, 10)
+	for i := range hashes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			frame, err := pc.CameraFrame()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			hashes[i] = frame.Hash
+		}(i)
+	}
+
+	// Give everyone a chance to pile up behind the first fetch
+	waitFor(t, func() bool { return fetches() == 1 })
+	time.Sleep(50 * time.Millisecond)
+	close(hold)
+	wg.Wait()
+
+	if n := fetches(); n != 1 {
+		t.Errorf("the printer was asked for %d frames by concurrent callers, want 1", n)
+	}
+
+	for _, h := range hashes {
+		if h != hashes[0] {
+			t.Errorf("callers got different frames: %v", hashes)
+			break
+		}
+	}
+
+	// Still within the interval, so the cached frame is served
+	if _, err := pc.CameraFrame(); err != nil || fetches() != 1 {
+		t.Errorf("got %v after %d fetches, want the cached frame", err, fetches())
+	}
+
+	ctx.Config.CameraFrameInterval = 1
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := pc.CameraFrame(); err != nil || fetches() != 2 {
+		t.Errorf("got %v after %d fetches, want a new frame once the interval passed", err, fetches())
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"net/url"
+	"testing"
+)
+
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	green = color.RGBA{0, 255, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+	white = color.RGBA{255, 255, 255, 255}
+)
+
+// testImage builds an image out of rows of pixels
+func testImage(rows ...[]color.RGBA) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, c := range row {
+			img.Set(x, y, c)
+		}
+	}
+
+	return img
+}
+
+// checkPixels compares `img` with rows of expected pixels
+func checkPixels(t *testing.T, name string, img image.Image, rows ...[]color.RGBA) {
+	t.Helper()
+
+	b := img.Bounds()
+	if b.Dx() != len(rows[0]) || b.Dy() != len(rows) {
+		t.Errorf("%s: got %dx%d, want %dx%d", name, b.Dx(), b.Dy(), len(rows[0]), len(rows))
+		return
+	}
+
+	for y, row := range rows {
+		for x, want := range row {
+			got := color.RGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y))
+			if got != want {
+				t.Errorf("%s: pixel (%d, %d) is %v, want %v", name, x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestParseSnapshotOptions(t *testing.T) {
+	tests := []struct {
+		query string
+		want  snapshotOptions
+		err   bool
+	}{
+		{"", snapshotOptions{Format: snapshotFormatJPEG, Quality: 75}, false},
+		{"width=0", snapshotOptions{Format: snapshotFormatJPEG, Quality: 75}, false},
+		{"width=320&quality=50&rotate=-90", snapshotOptions{Format: snapshotFormatJPEG, Width: 320, Quality: 50, Rotate: 270}, false},
+		{"rotate=450", snapshotOptions{Format: snapshotFormatJPEG, Quality: 75, Rotate: 90}, false},
+		{"width=-1", snapshotOptions{}, true},
+		{"width=5000", snapshotOptions{}, true},
+		{"width=wide", snapshotOptions{}, true},
+		{"quality=0", snapshotOptions{}, true},
+		{"rotate=45", snapshotOptions{}, true},
+	}
+
+	for _, test := range tests {
+		q, _ := url.ParseQuery(test.query)
+
+		got, err := parseSnapshotOptions(q, snapshotFormatJPEG)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: got %+v, want an error", test.query, got)
+			}
+			continue
+		}
+
+		if err != nil || got != test.want {
+			t.Errorf("%q: got %+v, %v, want %+v", test.query, got, err, test.want)
+		}
+	}
+}
+
+func TestYUYVToImage(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		width, height int
+		y, cb, cr     []uint8 // y has one entry per pixel, cb and cr one per pair of pixels
+		err           bool
+	}{
+		{"one pair", []byte{10, 100, 20, 200}, 2, 1, []uint8{10, 20}, []uint8{100}, []uint8{200}, false},
+		{"two rows", []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, 4, 2,
+			[]uint8{1, 3, 5, 7, 9, 11, 13, 15}, []uint8{2, 6, 10, 14}, []uint8{4, 8, 12, 16}, false},
+		{"odd width", []byte{1, 2, 3, 4, 5, 6}, 3, 1, nil, nil, nil, true},
+		{"short frame", []byte{1, 2, 3, 4}, 2, 2, nil, nil, nil, true},
+		{"no size", nil, 0, 0, nil, nil, nil, true},
+	}
+
+	for _, test := range tests {
+		img, err := yuyvToImage(test.data, test.width, test.height)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: got an image, want an error", test.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		ycc := img.(*image.YCbCr)
+		if b := ycc.Bounds(); b.Dx() != test.width || b.Dy() != test.height {
+			t.Errorf("%s: got %dx%d, want %dx%d", test.name, b.Dx(), b.Dy(), test.width, test.height)
+			continue
+		}
+
+		for i, want := range test.y {
+			x, y := i%test.width, i/test.width
+			if c := ycc.YCbCrAt(x, y); c.Y != want || c.Cb != test.cb[i/2] || c.Cr != test.cr[i/2] {
+				t.Errorf("%s: pixel (%d, %d) is %v, want Y %d Cb %d Cr %d", test.name, x, y, c, want, test.cb[i/2], test.cr[i/2])
+			}
+		}
+	}
+}
+
+func TestScaleImage(t *testing.T) {
+	img := testImage(
+		[]color.RGBA{red, green, blue, white},
+		[]color.RGBA{white, blue, green, red},
+	)
+
+	checkPixels(t, "original width", scaleImage(img, 0), []color.RGBA{red, green, blue, white}, []color.RGBA{white, blue, green, red})
+	checkPixels(t, "same width", scaleImage(img, 4), []color.RGBA{red, green, blue, white}, []color.RGBA{white, blue, green, red})
+	checkPixels(t, "half", scaleImage(img, 2), []color.RGBA{red, blue})
+	checkPixels(t, "odd width", scaleImage(img, 3), []color.RGBA{red, green, blue})
+	checkPixels(t, "double", scaleImage(img, 8),
+		[]color.RGBA{red, red, green, green, blue, blue, white, white},
+		[]color.RGBA{red, red, green, green, blue, blue, white, white},
+		[]color.RGBA{white, white, blue, blue, green, green, red, red},
+		[]color.RGBA{white, white, blue, blue, green, green, red, red},
+	)
+
+	// Too narrow to keep a row, but the image still has to have one
+	checkPixels(t, "one pixel", scaleImage(testImage([]color.RGBA{red, green, blue, white}), 1), []color.RGBA{red})
+}
+
+func TestRotateImage(t *testing.T) {
+	img := testImage(
+		[]color.RGBA{red, green, blue},
+		[]color.RGBA{white, red, green},
+	)
+
+	tests := []struct {
+		degrees int
+		want    [][]color.RGBA
+	}{
+		{0, [][]color.RGBA{{red, green, blue}, {white, red, green}}},
+		{90, [][]color.RGBA{{white, red}, {red, green}, {green, blue}}},
+		{180, [][]color.RGBA{{green, red, white}, {blue, green, red}}},
+		{270, [][]color.RGBA{{blue, green}, {green, red}, {red, white}}},
+	}
+
+	for _, test := range tests {
+		checkPixels(t, fmt.Sprintf("%d degrees", test.degrees), rotateImage(img, test.degrees), test.want...)
+	}
+}
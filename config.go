@@ -13,35 +13,65 @@ const (
 )
 
 type printerConfig struct {
-	ConnectionType string // ConnectionType should be either "local" or "remote". "local" = direct connect via IP, "remote" = remotely connect via MakerBot Reflector service.
-	ID             string // ID should be provided if the connection type is "remote". This is the ID of the printer as returned by MakerBot Reflector. It is usually the serial number.
-	IP             string // IP should be provided if the connection type is "local"
-	Port           string // Port should be provided if the connection type is "port"
+	Name           string   // Name is an optional stable name that the printer can be looked up by in the API, even while it is disconnected
+	Tags           []string // Tags is an optional list of labels used to filter the printer list
+	ConnectionType string   // ConnectionType should be "local", "remote" or "moonraker". "local" = direct connect via IP, "remote" = remotely connect via MakerBot Reflector service, "moonraker" = a Klipper printer via Moonraker's API at IP and Port.
+	ID             string   // ID should be provided if the connection type is "remote". This is the ID of the printer as returned by MakerBot Reflector. It is usually the serial number.
+	IP             string   // IP should be provided if the connection type is "local"
+	Port           string   // Port should be provided if the connection type is "port"
+	APIKey         string   // APIKey is sent to Moonraker if its API requires one
+	AuthPort       string   // AuthPort is the port a "local" printer serves its authentication endpoint on, if not 80. makerbot-rpc's own connection always uses 80, so this is mostly useful for testing against `makerbotd simulate`.
 }
 
 type config struct {
-	Debug               bool            // Debug makes output more verbose
-	ThingiverseUsername string          // ThingiverseUsername defines the username of the authenticated Thingiverse account
-	ThingiverseToken    string          // ThingiverseToken defines the auth token of the authenticated Thingiverse account
-	ListenSocket        bool            // ListenSocket defines whether or not makerbotd will listen on a unix domain socket
-	ListenSocketPath    string          // ListenSocketPath defines the unix domain socket to listen on if ListenSocket is true
-	ListenTCP           bool            // ListenTCP defines whether or not makerbotd will listen on a TCP port
-	ListenTCPAddress    string          // ListenTCPPort defines the TCP port to listen on if ListenTCP is true
-	AutoAddPrinters     bool            // AutoAddPrinters defines whether or not printers should automatically be added from the authenticated Thingiverse account (DOES NOTHING RIGHT NOW)
-	ReadOnly            bool            // ReadOnly makes the API exposed by makerbotd read-only, e.g. print jobs cannot be sent, cancelled, etc. This is useful if you are publicly exposing the makerbotd API.
-	Printers            []printerConfig // Printers is the list of MakerBot printers that will automatically be connected when makerbotd starts
+	Debug                bool                         // Debug makes output more verbose
+	ThingiverseUsername  string                       // ThingiverseUsername defines the username of the authenticated Thingiverse account
+	ThingiverseToken     string                       // ThingiverseToken defines the auth token of the authenticated Thingiverse account
+	ListenSocket         bool                         // ListenSocket defines whether or not makerbotd will listen on a unix domain socket
+	ListenSocketPath     string                       // ListenSocketPath defines the unix domain socket to listen on if ListenSocket is true
+	ListenTCP            bool                         // ListenTCP defines whether or not makerbotd will listen on a TCP port
+	ListenTCPAddress     string                       // ListenTCPPort defines the TCP port to listen on if ListenTCP is true
+	ListenGRPC           bool                         // ListenGRPC defines whether or not makerbotd will serve its gRPC service on a separate listener
+	ListenGRPCAddress    string                       // ListenGRPCAddress defines the TCP address, or "unix:" followed by a unix domain socket path, the gRPC service listens on if ListenGRPC is true
+	AutoAddPrinters      bool                         // AutoAddPrinters defines whether or not printers should automatically be added from the authenticated Thingiverse account
+	AutoAddInterval      int                          // AutoAddInterval defines how often, in seconds, the account's printer list is refreshed if AutoAddPrinters is true
+	ReflectorBaseURL     string                       // ReflectorBaseURL defines the base URL of the MakerBot Reflector service used to list the account's printers
+	HeartbeatInterval    int                          // HeartbeatInterval defines how often, in seconds, each printer connection is checked for liveness. 0 disables the check.
+	HeartbeatTimeout     int                          // HeartbeatTimeout defines how long, in seconds, a printer can go without sending anything or answering a heartbeat ping before it is considered disconnected
+	ReadyMinPrinters     int                          // ReadyMinPrinters defines how many printers must be connected for /readyz to report ready
+	ReadyPrinters        []string                     // ReadyPrinters is a list of printer names or serials that must be connected for /readyz to report ready
+	OctoPrintAPI         bool                         // OctoPrintAPI enables the OctoPrint-compatible API that slicers can upload prints to
+	OctoPrintAPIKey      string                       // OctoPrintAPIKey is the API key slicers must send to the OctoPrint-compatible API. If empty, no key is required.
+	OctoPrintPrinter     string                       // OctoPrintPrinter is the name or serial of the printer that uploads to /api/files/local are sent to
+	AdminToken           string                       // AdminToken enables admin-only endpoints, like raw printer RPC calls. It must be sent as a bearer token in the Authorization header.
+	SpoolsPath           string                       // SpoolsPath defines where the filament spool inventory is saved. Defaults to spools.json next to the config file.
+	RefuseLowFilament    bool                         // RefuseLowFilament makes makerbotd refuse prints that need more filament than the loaded spool has left, instead of just warning
+	CameraFrameInterval  int                          // CameraFrameInterval defines the minimum time, in milliseconds, between camera frame requests to each printer. Everyone asking for a frame in between gets the cached one.
+	PrintURLAllowedHosts []string                     // PrintURLAllowedHosts is the list of hosts that prints can be downloaded from. "*.example.com" allows every subdomain. Printing from a URL is disabled if it is empty.
+	PrintURLMaxSize      int64                        // PrintURLMaxSize defines the largest print file, in bytes, that will be downloaded
+	PrintURLHeaders      map[string]map[string]string // PrintURLHeaders defines extra headers, e.g. for authentication, sent when downloading prints from each host
+	MaxUploadSize        int64                        // MaxUploadSize defines the largest print file, in bytes, that can be uploaded
+	ReadOnly             bool                         // ReadOnly makes the API exposed by makerbotd read-only, e.g. print jobs cannot be sent, cancelled, etc. This is useful if you are publicly exposing the makerbotd API.
+	Printers             []printerConfig              // Printers is the list of MakerBot printers that will automatically be connected when makerbotd starts
 }
 
 func writeDefaultConfig(path string) (*config, error) {
 	dc := config{
-		Debug:            false,
-		AutoAddPrinters:  false,
-		ListenSocket:     true,
-		ListenSocketPath: "/var/run/makerbot.socket",
-		ListenTCP:        false,
-		ListenTCPAddress: ":6969", // nice
-		ReadOnly:         false,
-		Printers:         []printerConfig{},
+		Debug:               false,
+		AutoAddPrinters:     false,
+		AutoAddInterval:     300,
+		ReflectorBaseURL:    defaultReflectorBaseURL,
+		HeartbeatInterval:   15,
+		HeartbeatTimeout:    60,
+		CameraFrameInterval: 1000,
+		ListenSocket:        true,
+		ListenSocketPath:    "/var/run/makerbot.socket",
+		ListenTCP:           false,
+		ListenTCPAddress:    ":6969", // nice
+		ListenGRPC:          false,
+		ListenGRPCAddress:   ":6970",
+		ReadOnly:            false,
+		Printers:            []printerConfig{},
 	}
 
 	conf, err := json.MarshalIndent(dc, "", "  ")
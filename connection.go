@@ -2,54 +2,120 @@ package main
 
 import (
 	"errors"
+	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/tjhorner/makerbot-rpc"
 )
 
 type printerConnection struct {
-	Connected  bool
-	context    *mbContext
-	config     printerConfig
-	connection *makerbot.Client
+	Connected   bool
+	context     *mbContext
+	config      printerConfig
+	connection  printerBackend
+	serial      string // serial is remembered from the last successful connection
+	machineName string // machineName is remembered from the last successful connection
+	mu          sync.Mutex
+	lastSeen    time.Time // lastSeen is the last time the printer sent us anything
+
+	filamentUsage []float64 // filamentUsage is how much filament the last print we sent needs from each tool
+	camera        cameraBroker
+	revision      uint64            // revision is bumped every time the printer's state changes
+	state         *makerbot.Printer // state is the printer's info and state as of revision
+	changed       chan struct{}     // changed is closed when revision is bumped
+
+	expectedDuration float64     // expectedDuration is how long the last print we sent should take, in seconds
+	job              *jobHistory // job is the progress history of the current process
 }
 
-type printerConnections []*printerConnection
+var errPrinterNotConnected = errors.New("printer is not connected")
+
+type printerConnections struct {
+	sync.RWMutex
+	list []*printerConnection
+}
+
+func (pcs *printerConnections) Add(conn *printerConnection) {
+	pcs.Lock()
+	defer pcs.Unlock()
+
+	pcs.list = append(pcs.list, conn)
+}
+
+// Has returns true if a printer with the Reflector ID or serial `id` is already
+// configured, whether or not it is currently connected
+func (pcs *printerConnections) Has(id string) bool {
+	pcs.RLock()
+	defer pcs.RUnlock()
+
+	for _, c := range pcs.list {
+		if c.config.ID == id || c.Serial() == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CloseAll closes every printer connection
+func (pcs *printerConnections) CloseAll() {
+	pcs.RLock()
+	defer pcs.RUnlock()
+
+	for _, c := range pcs.list {
+		err := c.Close()
+		if err != nil {
+			c.context.Debugf("printerConnection: error while closing: %v\n", err)
+		}
+	}
+}
+
+// ConnectedPrinters lists every connected printer. If `tag` is not empty, only
+// printers configured with that tag are included.
+func (pcs *printerConnections) ConnectedPrinters(tag string) *[]makerbot.Printer {
+	pcs.RLock()
+	defer pcs.RUnlock()
 
-func (pcs *printerConnections) ConnectedPrinters() *[]makerbot.Printer {
 	printers := []makerbot.Printer{}
-	for _, c := range *pcs {
-		if !c.Connected {
+	for _, c := range pcs.list {
+		conn := c.backend()
+		if conn == nil || (tag != "" && !c.HasTag(tag)) {
 			continue
 		}
 
-		printers = append(printers, *c.connection.Printer)
+		printers = append(printers, *conn.Printer())
 	}
 
 	return &printers
 }
 
+// Find looks up a printer by its configured name, serial or machine name. Printers
+// are found even while they are disconnected, as long as they have been configured
+// with a name or ID or have connected at least once.
 func (pcs *printerConnections) Find(q string) (conn *printerConnection, ok bool) {
-	ok = false
+	pcs.RLock()
+	defer pcs.RUnlock()
 
-	for _, c := range *pcs {
-		if !c.Connected || !(c.connection.Printer.Serial == q || strings.EqualFold(c.connection.Printer.MachineName, q)) {
-			continue
+	for _, c := range pcs.list {
+		if c.Matches(q) {
+			return c, true
 		}
-
-		conn = c
-		ok = true
 	}
 
-	return conn, ok
+	return nil, false
 }
 
 func (pcs *printerConnections) BySerial(serial string) (conn *printerConnection, ok bool) {
+	pcs.RLock()
+	defer pcs.RUnlock()
+
 	ok = false
 
-	for _, c := range *pcs {
-		if !c.Connected || c.connection.Printer.Serial != serial {
+	for _, c := range pcs.list {
+		backend := c.backend()
+		if backend == nil || backend.Printer().Serial != serial {
 			continue
 		}
 
@@ -60,69 +126,178 @@ func (pcs *printerConnections) BySerial(serial string) (conn *printerConnection,
 	return conn, ok
 }
 
+// Matches returns true if `q` identifies this printer
+func (pc *printerConnection) Matches(q string) bool {
+	if pc.config.Name != "" && strings.EqualFold(pc.config.Name, q) {
+		return true
+	}
+
+	id := pc.ID()
+	serial, machineName := pc.identity()
+
+	if (id != "" && id == q) || (serial != "" && serial == q) {
+		return true
+	}
+
+	return machineName != "" && strings.EqualFold(machineName, q)
+}
+
+// HasTag returns true if the printer was configured with `tag`
+func (pc *printerConnection) HasTag(tag string) bool {
+	for _, t := range pc.config.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rememberPrinter keeps the printer's identity around so it can still be
+// found after it disconnects
+func (pc *printerConnection) rememberPrinter(p *makerbot.Printer) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.serial = p.Serial
+	pc.machineName = p.MachineName
+}
+
+// identity returns the serial and machine name remembered from the last
+// successful connection
+func (pc *printerConnection) identity() (serial, machineName string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	return pc.serial, pc.machineName
+}
+
+// Serial returns the serial remembered from the last successful connection
+func (pc *printerConnection) Serial() string {
+	serial, _ := pc.identity()
+	return serial
+}
+
+// ID identifies the printer for as long as makerbotd runs. It comes from the
+// configuration, so unlike the serial it is known before the printer first
+// connects and doesn't change once it does: the Reflector ID if one was
+// configured, or else the printer's address.
+func (pc *printerConnection) ID() string {
+	switch {
+	case pc.config.ID != "":
+		return pc.config.ID
+	case pc.config.Port != "":
+		return net.JoinHostPort(pc.config.IP, pc.config.Port)
+	}
+
+	return pc.config.IP
+}
+
+// backend returns the printer's connection, or nil if it isn't connected
+func (pc *printerConnection) backend() printerBackend {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if !pc.Connected {
+		return nil
+	}
+
+	return pc.connection
+}
+
 func newPrinterConnection(context *mbContext, conf printerConfig) *printerConnection {
 	return &printerConnection{Connected: false, context: context, config: conf}
 }
 
-func (pc *printerConnection) handleDisconnect() {
-	pc.context.Debugln("printerConnection: disconnected! attempting reconnect in 10s...")
+func (pc *printerConnection) handleStateChange(old, new *makerbot.PrinterMetadata) {
+	pc.touch()
+	pc.trackFilament(old, new)
+	pc.sampleProgress(new)
+	pc.bumpRevision()
+}
+
+func (pc *printerConnection) handleDisconnect(conn printerBackend) {
+	pc.mu.Lock()
+	if pc.connection != conn {
+		// This connection's disconnect was already handled, e.g. by the heartbeat
+		pc.mu.Unlock()
+		return
+	}
+
 	pc.Connected = false
 	pc.connection = nil
+	pc.mu.Unlock()
 
-	time.Sleep(10 * time.Second)
-	pc.Connect()
-}
-
-func (pc *printerConnection) connectLocal() error {
-	pc.context.Debugf("printerConnection: connecting local (%s, %s)...\n", pc.config.IP, pc.config.Port)
+	pc.bumpRevision()
 
-	err := pc.connection.ConnectLocal(pc.config.IP, pc.config.Port)
-	if err != nil {
-		return err
+	if pc.context.ShuttingDown() {
+		return
 	}
 
-	pc.context.Debugf("printerConnection: connected, authenticating (%s, %s)...\n", pc.config.IP, pc.config.Port)
+	pc.context.Debugln("printerConnection: disconnected! attempting reconnect in 10s...")
 
-	err = pc.connection.AuthenticateWithThingiverse(pc.context.Config.ThingiverseToken, pc.context.Config.ThingiverseUsername)
-	if err != nil {
-		return err
+	select {
+	case <-pc.context.done:
+		return
+	case <-time.After(10 * time.Second):
 	}
 
-	pc.Connected = true
-	pc.context.Debugf("printerConnection: connected to %s!\n", pc.connection.Printer.MachineName)
-	return nil
+	pc.context.Metrics.CountReconnect(pc.ID())
+	pc.Connect()
 }
 
-func (pc *printerConnection) connectRemote() error {
-	pc.context.Debugln("printerConnection: connecting remote...")
+// Close disconnects from the printer. It will not be reconnected if makerbotd
+// is shutting down.
+func (pc *printerConnection) Close() error {
+	pc.mu.Lock()
+	conn := pc.connection
+	pc.mu.Unlock()
 
-	err := pc.connection.ConnectRemote(pc.config.ID, pc.context.Config.ThingiverseToken)
-	if err != nil {
+	if conn == nil {
 		return nil
 	}
 
-	pc.Connected = true
-	pc.context.Debugln("printerConnection: connected!")
-	return nil
+	return conn.Close()
 }
 
 func (pc *printerConnection) Connect() error {
 	pc.context.Debugln("printerConnection: Connect() called...")
 
-	cl := makerbot.NewClient()
-	cl.Timeout = 10 * time.Second
+	if pc.context.ShuttingDown() {
+		return errors.New("makerbotd is shutting down")
+	}
+
+	newBackend, ok := printerBackends[pc.config.ConnectionType]
+	if !ok {
+		return errors.New("connection type is wrong")
+	}
 
-	cl.HandleDisconnect(pc.handleDisconnect)
+	conn := newBackend(pc.context, pc.config)
 
-	pc.connection = &cl
+	conn.HandleDisconnect(func() { pc.handleDisconnect(conn) })
+	conn.HandleStateChange(pc.handleStateChange)
 
-	if pc.config.ConnectionType == connectionTypeLocal {
-		return pc.connectLocal()
-	}
+	pc.mu.Lock()
+	pc.connection = conn
+	pc.mu.Unlock()
 
-	if pc.config.ConnectionType == connectionTypeRemote {
-		return pc.connectRemote()
+	err := conn.Connect()
+	if err != nil {
+		return err
 	}
 
-	return errors.New("connection type is wrong")
+	pc.rememberPrinter(conn.Printer())
+
+	pc.mu.Lock()
+	// The connection may already have dropped again
+	pc.Connected = pc.connection == conn
+	pc.mu.Unlock()
+
+	pc.bumpRevision()
+	pc.context.Debugf("printerConnection: connected to %s!\n", conn.Printer().MachineName)
+
+	pc.touch()
+	go pc.heartbeat(conn)
+
+	return nil
 }
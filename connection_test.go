@@ -0,0 +1,44 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestFindWhileConnecting(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	fakes := useFakeBackend(t, connectionTypeLocal)
+
+	pc := newPrinterConnection(ctx, printerConfig{ConnectionType: connectionTypeLocal, ID: "1", IP: "10.0.0.1"})
+	ctx.Printers.Add(pc)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		pc.Connect()
+	}()
+
+	// Look the printer up while it connects, so the race detector can catch
+	// unguarded access to what it remembers about the printer
+	for i := 0; i < 100; i++ {
+		ctx.Printers.Find("FAKE1")
+		ctx.Printers.Has("FAKE1")
+	}
+	wg.Wait()
+
+	if len(fakes.Created()) != 1 {
+		t.Fatal("no backend was created")
+	}
+
+	for _, q := range []string{"FAKE1", "fake 1", "1"} {
+		found, ok := ctx.Printers.Find(q)
+		if !ok || found != pc {
+			t.Errorf("Find(%q) did not find the printer", q)
+		}
+	}
+
+	if !ctx.Printers.Has("FAKE1") {
+		t.Error("Has did not find the printer by its serial")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+const (
+	// discoveryAddress is where MakerBot printers listen for discovery broadcasts
+	discoveryAddress = "255.255.255.255:12307"
+	// discoveryTimeout is how long we wait for printers to answer a broadcast
+	discoveryTimeout = 3 * time.Second
+)
+
+type discoveredPrinter struct {
+	IP          string        `json:"ip"`
+	Port        string        `json:"port"`
+	Serial      string        `json:"serial"`
+	MachineName string        `json:"machine_name"`
+	Config      printerConfig `json:"config"`
+}
+
+// discoverPrinters broadcasts a MakerBot discovery request to `addr` and collects
+// every printer that answers within `timeout`. Printers reply directly to the
+// address the broadcast came from with the same machine info the RPC handshake
+// returns.
+func discoverPrinters(addr string, timeout time.Duration) ([]discoveredPrinter, error) {
+	raddr, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	req, _ := json.Marshal(map[string]string{"command": "broadcast"})
+
+	_, err = conn.WriteToUDP(req, raddr)
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.SetReadDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return nil, err
+	}
+
+	found := []discoveredPrinter{}
+	seen := map[string]bool{}
+	buf := make([]byte, 4096)
+
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				break
+			}
+
+			return nil, err
+		}
+
+		var p makerbot.Printer
+		if json.Unmarshal(buf[:n], &p) != nil || p.Serial == "" || seen[p.Serial] {
+			continue
+		}
+		seen[p.Serial] = true
+
+		// Some firmware versions leave the IP out, so fall back to whoever answered
+		if p.IP == "" {
+			p.IP = from.IP.String()
+		}
+
+		if p.Port == "" {
+			p.Port = "9999"
+		}
+
+		found = append(found, discoveredPrinter{
+			IP:          p.IP,
+			Port:        p.Port,
+			Serial:      p.Serial,
+			MachineName: p.MachineName,
+			Config: printerConfig{
+				ConnectionType: connectionTypeLocal,
+				IP:             p.IP,
+				Port:           p.Port,
+			},
+		})
+	}
+
+	return found, nil
+}
+
+// discoverCommand implements `makerbotd discover`. It prints every printer it
+// finds along with a printerConfig that can be pasted into the config file.
+func discoverCommand() {
+	log.Println("Looking for printers on the local network...")
+
+	printers, err := discoverPrinters(discoveryAddress, discoveryTimeout)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if len(printers) == 0 {
+		log.Println("No printers found.")
+		return
+	}
+
+	configs := []printerConfig{}
+	for _, p := range printers {
+		fmt.Fprintf(os.Stderr, "%s (%s) at %s:%s\n", p.MachineName, p.Serial, p.IP, p.Port)
+		configs = append(configs, p.Config)
+	}
+
+	out, _ := json.MarshalIndent(configs, "", "  ")
+	fmt.Println(string(out))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+// respondToDiscovery answers the first broadcast it gets on conn with every
+// reply in `replies`, in order
+func respondToDiscovery(t *testing.T, conn *net.UDPConn, replies ...string) {
+	buf := make([]byte, 4096)
+	n, from, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		t.Errorf("reading broadcast: %v", err)
+		return
+	}
+
+	var req map[string]string
+	if err := json.Unmarshal(buf[:n], &req); err != nil || req["command"] != "broadcast" {
+		t.Errorf("unexpected discovery request %q", buf[:n])
+		return
+	}
+
+	for _, reply := range replies {
+		conn.WriteToUDP([]byte(reply), from)
+	}
+}
+
+func TestDiscoverPrinters(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	go respondToDiscovery(t, conn,
+		`{"iserial": "SERIAL1", "machine_name": "First", "ip": "10.0.0.5", "port": "9999"}`,
+		`{"iserial": "SERIAL1", "machine_name": "First again", "ip": "10.0.0.5", "port": "9999"}`,
+		`not json`,
+		`{"machine_name": "No serial"}`,
+		`{"iserial": "SERIAL2", "machine_name": "Second"}`,
+	)
+
+	found, err := discoverPrinters(conn.LocalAddr().String(), 500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(found) != 2 {
+		t.Fatalf("found %d printers, want 2: %+v", len(found), found)
+	}
+
+	first := found[0]
+	if first.Serial != "SERIAL1" || first.MachineName != "First" || first.IP != "10.0.0.5" {
+		t.Errorf("unexpected first printer %+v", first)
+	}
+
+	second := found[1]
+	if second.IP != "127.0.0.1" {
+		t.Errorf("second printer's IP = %q, want the address it answered from", second.IP)
+	}
+	if second.Port != "9999" {
+		t.Errorf("second printer's port = %q, want the default", second.Port)
+	}
+
+	want := printerConfig{ConnectionType: connectionTypeLocal, IP: "127.0.0.1", Port: "9999"}
+	if second.Config.ConnectionType != want.ConnectionType || second.Config.IP != want.IP || second.Config.Port != want.Port {
+		t.Errorf("second printer's config = %+v, want %+v", second.Config, want)
+	}
+}
+
+func TestDiscoverPrintersNoAnswer(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	found, err := discoverPrinters(conn.LocalAddr().String(), 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(found) != 0 {
+		t.Errorf("found %d printers, want none", len(found))
+	}
+}
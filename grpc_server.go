@@ -0,0 +1,646 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image/jpeg"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"makerbotd/makerbotdpb"
+
+	"github.com/tjhorner/makerbot-rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcServer implements the makerbotd gRPC service. It does what APIv2 does,
+// with printer state and camera frames streamed instead of polled.
+type grpcServer struct {
+	makerbotdpb.UnimplementedMakerbotdServer
+	context *mbContext
+}
+
+// newGRPCServer sets up a gRPC server with the makerbotd service registered
+func newGRPCServer(ctx *mbContext) *grpc.Server {
+	server := grpc.NewServer()
+	makerbotdpb.RegisterMakerbotdServer(server, &grpcServer{context: ctx})
+
+	return server
+}
+
+// writable fails with PermissionDenied if makerbotd is read-only
+func (s *grpcServer) writable() error {
+	if s.context.Config.ReadOnly {
+		return status.Error(codes.PermissionDenied, "makerbotd is read-only")
+	}
+
+	return nil
+}
+
+// findPrinter looks up a printer by its name, serial or machine name. If
+// `connected` is true, it fails with Unavailable if the printer isn't connected
+// right now.
+func (s *grpcServer) findPrinter(id string, connected bool) (*printerConnection, error) {
+	printer, ok := s.context.Printers.Find(id)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "printer not found")
+	}
+
+	if connected && printer.backend() == nil {
+		return nil, status.Error(codes.Unavailable, "printer is not connected")
+	}
+
+	return printer, nil
+}
+
+func protoTime(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+
+	return timestamppb.New(*t)
+}
+
+func protoPrinter(pc *printerConnection) *makerbotdpb.Printer {
+	p := newV2Printer(pc)
+
+	pp := &makerbotdpb.Printer{
+		Id:             p.ID,
+		Name:           p.Name,
+		Serial:         p.Serial,
+		MachineName:    p.MachineName,
+		ConnectionType: p.ConnectionType,
+		Tags:           p.Tags,
+		Connected:      p.Connected,
+		Revision:       p.Revision,
+	}
+
+	if info := p.Info; info != nil {
+		pp.Info = &makerbotdpb.PrinterInfo{
+			MachineType: info.MachineType,
+			MachineName: info.MachineName,
+			BotType:     info.BotType,
+			Ip:          info.IP,
+			ApiVersion:  info.APIVersion,
+		}
+
+		if fw := info.FirmwareVersion; fw != nil {
+			pp.Info.FirmwareVersion = fmt.Sprintf("%d.%d.%d.%d", fw.Major, fw.Minor, fw.Bugfix, fw.Build)
+		}
+	}
+
+	return pp
+}
+
+func protoJob(job *jobProgress) *makerbotdpb.Job {
+	if job == nil {
+		return nil
+	}
+
+	pj := &makerbotdpb.Job{
+		Id:          int32(job.ID),
+		Name:        job.Name,
+		Step:        job.Step,
+		Progress:    int32(job.Progress),
+		Cancellable: job.Cancellable,
+		Cancelled:   job.Cancelled,
+		Complete:    job.Complete,
+		Methods:     job.Methods,
+		ElapsedTime: int32(job.ElapsedTime),
+		Filename:    job.Filename,
+		StartedAt:   protoTime(job.StartedAt),
+		Eta:         protoTime(job.ETA),
+	}
+
+	if job.Reason != nil {
+		pj.Reason = *job.Reason
+	}
+
+	return pj
+}
+
+func (s *grpcServer) protoTools(printer *printerConnection) []*makerbotdpb.Tool {
+	tools := []*makerbotdpb.Tool{}
+
+	for _, t := range (&APIv2{context: s.context}).tools(printer) {
+		tool := &makerbotdpb.Tool{
+			Index:              int32(t.Index),
+			CurrentTemperature: int32(t.CurrentTemperature),
+			TargetTemperature:  int32(t.TargetTemperature),
+			FilamentPresent:    t.FilamentPresent,
+			ToolPresent:        t.ToolPresent,
+		}
+
+		if t.Spool != nil {
+			tool.SpoolId = t.Spool.ID
+		}
+
+		tools = append(tools, tool)
+	}
+
+	return tools
+}
+
+func protoOperation(op operation) *makerbotdpb.Operation {
+	po := &makerbotdpb.Operation{
+		Id:        op.ID,
+		Type:      op.Type,
+		Printer:   op.Printer,
+		Status:    op.Status,
+		Step:      op.Step,
+		Error:     op.Error,
+		CreatedAt: timestamppb.New(op.CreatedAt),
+		UpdatedAt: timestamppb.New(op.UpdatedAt),
+	}
+
+	if t := op.Transfer; t != nil {
+		po.Transfer = &makerbotdpb.TransferProgress{
+			BytesSent:  t.BytesSent,
+			Total:      t.Total,
+			Rate:       t.Rate,
+			EtaSeconds: t.ETA,
+		}
+	}
+
+	return po
+}
+
+func (s *grpcServer) ListPrinters(_ context.Context, req *makerbotdpb.ListPrintersRequest) (*makerbotdpb.ListPrintersResponse, error) {
+	s.context.Printers.RLock()
+	defer s.context.Printers.RUnlock()
+
+	res := &makerbotdpb.ListPrintersResponse{Printers: []*makerbotdpb.Printer{}}
+	for _, pc := range s.context.Printers.list {
+		if req.Tag == "" || pc.HasTag(req.Tag) {
+			res.Printers = append(res.Printers, protoPrinter(pc))
+		}
+	}
+
+	return res, nil
+}
+
+func (s *grpcServer) GetPrinter(_ context.Context, req *makerbotdpb.GetPrinterRequest) (*makerbotdpb.Printer, error) {
+	printer, err := s.findPrinter(req.PrinterId, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return protoPrinter(printer), nil
+}
+
+func (s *grpcServer) GetJob(_ context.Context, req *makerbotdpb.GetJobRequest) (*makerbotdpb.Job, error) {
+	printer, err := s.findPrinter(req.PrinterId, true)
+	if err != nil {
+		return nil, err
+	}
+
+	job := printer.JobProgress()
+	if job == nil {
+		return nil, status.Error(codes.NotFound, "printer has no current job")
+	}
+
+	return protoJob(job), nil
+}
+
+// printChunkReader reads the print file from the chunks that follow a Print header
+type printChunkReader struct {
+	stream makerbotdpb.Makerbotd_PrintServer
+	buf    []byte
+}
+
+func (pr *printChunkReader) Read(p []byte) (int, error) {
+	for len(pr.buf) == 0 {
+		req, err := pr.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+
+		if req.GetHeader() != nil {
+			return 0, status.Error(codes.InvalidArgument, "header can only be sent once")
+		}
+
+		pr.buf = req.GetChunk()
+	}
+
+	n := copy(p, pr.buf)
+	pr.buf = pr.buf[n:]
+
+	return n, nil
+}
+
+func (s *grpcServer) Print(stream makerbotdpb.Makerbotd_PrintServer) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	header := req.GetHeader()
+	if header == nil || header.Filename == "" {
+		return status.Error(codes.InvalidArgument, "the first message must be a header with a filename")
+	}
+
+	printer, err := s.findPrinter(header.PrinterId, true)
+	if err != nil {
+		return err
+	}
+
+	max := s.context.maxUploadSize()
+
+	file, size, err := spoolUpload(&printChunkReader{stream: stream}, max)
+	if err == errUploadTooLarge {
+		return status.Errorf(codes.ResourceExhausted, "print files can be at most %d bytes", max)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	op, err := s.startPrint(stream.Context(), printer, path.Base(header.Filename), file, size)
+	if err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(op)
+}
+
+func (s *grpcServer) PrintURL(_ context.Context, req *makerbotdpb.PrintURLRequest) (*makerbotdpb.Operation, error) {
+	if err := s.writable(); err != nil {
+		return nil, err
+	}
+
+	if req.Url == "" {
+		return nil, status.Error(codes.InvalidArgument, "url is required")
+	}
+
+	if len(s.context.Config.PrintURLAllowedHosts) == 0 {
+		return nil, status.Error(codes.PermissionDenied, "printing from URLs is not enabled")
+	}
+
+	printer, err := s.findPrinter(req.PrinterId, true)
+	if err != nil {
+		return nil, err
+	}
+
+	pr := printURLRequest{URL: req.Url, Headers: req.Headers}
+
+	_, err = s.context.checkPrintURL(pr.URL)
+	if err == errHostNotAllowed {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return protoOperation(printer.PrintURL(pr)), nil
+}
+
+func (s *grpcServer) startPrint(ctx context.Context, printer *printerConnection, name string, file *os.File, size int64) (*makerbotdpb.Operation, error) {
+	op, warning, err := s.context.printUpload(printer, name, file, size)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	if warning != "" {
+		grpc.SetHeader(ctx, metadata.Pairs("warning", warning))
+	}
+
+	return protoOperation(op), nil
+}
+
+func (s *grpcServer) CancelJob(_ context.Context, req *makerbotdpb.CancelJobRequest) (*makerbotdpb.CancelJobResponse, error) {
+	if err := s.writable(); err != nil {
+		return nil, err
+	}
+
+	printer, err := s.findPrinter(req.PrinterId, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if printer.currentProcess() == nil {
+		return nil, status.Error(codes.NotFound, "printer has no current job")
+	}
+
+	conn := printer.backend()
+	if conn == nil {
+		return nil, status.Error(codes.Unavailable, errPrinterNotConnected.Error())
+	}
+
+	err = conn.Cancel()
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return &makerbotdpb.CancelJobResponse{}, nil
+}
+
+func (s *grpcServer) RunJobAction(_ context.Context, req *makerbotdpb.RunJobActionRequest) (*makerbotdpb.RunJobActionResponse, error) {
+	if err := s.writable(); err != nil {
+		return nil, err
+	}
+
+	if req.Action == "" {
+		return nil, status.Error(codes.InvalidArgument, "action is required")
+	}
+
+	printer, err := s.findPrinter(req.PrinterId, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if printer.currentProcess() == nil {
+		return nil, status.Error(codes.NotFound, "printer has no current job")
+	}
+
+	methods := currentMethods(printer)
+
+	valid := false
+	for _, m := range methods {
+		if m == req.Action {
+			valid = true
+			break
+		}
+	}
+
+	if !valid {
+		return nil, status.Errorf(codes.FailedPrecondition, "action %q is not available right now, available actions: %s", req.Action, strings.Join(methods, ", "))
+	}
+
+	conn := printer.backend()
+	if conn == nil {
+		return nil, status.Error(codes.Unavailable, errPrinterNotConnected.Error())
+	}
+
+	switch req.Action {
+	case "suspend":
+		err = conn.Suspend()
+	case "resume":
+		err = conn.Resume()
+	default:
+		err = conn.ProcessMethod(req.Action)
+	}
+
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return &makerbotdpb.RunJobActionResponse{}, nil
+}
+
+// findTool checks that the connected printer has a tool at `index`
+func (s *grpcServer) findTool(id string, index int32) (*printerConnection, error) {
+	printer, err := s.findPrinter(id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range s.protoTools(printer) {
+		if t.Index == index {
+			return printer, nil
+		}
+	}
+
+	return nil, status.Error(codes.NotFound, "tool not found")
+}
+
+func (s *grpcServer) LoadFilament(_ context.Context, req *makerbotdpb.LoadFilamentRequest) (*makerbotdpb.Operation, error) {
+	if err := s.writable(); err != nil {
+		return nil, err
+	}
+
+	printer, err := s.findTool(req.PrinterId, req.Tool)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := s.context.Spools.Get(req.SpoolId); req.SpoolId != "" && !ok {
+		return nil, status.Error(codes.InvalidArgument, "spool not found")
+	}
+
+	op := printer.StartProcess(operationTypeLoadFilament, func(conn printerBackend) error {
+		err := conn.LoadFilament(int(req.Tool))
+		if err != nil || req.SpoolId == "" {
+			return err
+		}
+
+		return s.context.Spools.Assign(req.SpoolId, printer.ID(), int(req.Tool))
+	})
+
+	return protoOperation(op), nil
+}
+
+func (s *grpcServer) UnloadFilament(_ context.Context, req *makerbotdpb.UnloadFilamentRequest) (*makerbotdpb.Operation, error) {
+	if err := s.writable(); err != nil {
+		return nil, err
+	}
+
+	printer, err := s.findTool(req.PrinterId, req.Tool)
+	if err != nil {
+		return nil, err
+	}
+
+	op := printer.StartProcess(operationTypeUnloadFilament, func(conn printerBackend) error {
+		err := conn.UnloadFilament(int(req.Tool))
+		if err != nil {
+			return err
+		}
+
+		return s.context.Spools.Unassign(printer.ID(), int(req.Tool))
+	})
+
+	return protoOperation(op), nil
+}
+
+func (s *grpcServer) GetOperation(_ context.Context, req *makerbotdpb.GetOperationRequest) (*makerbotdpb.Operation, error) {
+	op, ok := s.context.Operations.Get(req.OperationId)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "operation not found")
+	}
+
+	return protoOperation(op), nil
+}
+
+// WatchPrinter sends the printer's state every time its revision is bumped. It
+// keeps going while the printer is disconnected, so clients see it come back.
+func (s *grpcServer) WatchPrinter(req *makerbotdpb.WatchPrinterRequest, stream makerbotdpb.Makerbotd_WatchPrinterServer) error {
+	printer, err := s.findPrinter(req.PrinterId, false)
+	if err != nil {
+		return err
+	}
+
+	done := stream.Context().Done()
+
+	var rev uint64
+	for {
+		state := &makerbotdpb.PrinterState{
+			Printer: protoPrinter(printer),
+			Tools:   []*makerbotdpb.Tool{},
+		}
+
+		if state.Printer.Connected {
+			state.Job = protoJob(printer.JobProgress())
+			state.Tools = s.protoTools(printer)
+		}
+
+		if err := stream.Send(state); err != nil {
+			return err
+		}
+
+		rev = state.Printer.Revision
+
+		for {
+			next := printer.WaitForRevision(rev, maxStateWait, done)
+
+			select {
+			case <-done:
+				return nil
+			case <-s.context.done:
+				return status.Error(codes.Unavailable, "makerbotd is shutting down")
+			default:
+			}
+
+			if next > rev {
+				break
+			}
+		}
+	}
+}
+
+// WatchCamera sends a frame every time the printer's camera broker has a new
+// one. Frames that haven't changed aren't sent again.
+func (s *grpcServer) WatchCamera(req *makerbotdpb.WatchCameraRequest, stream makerbotdpb.Makerbotd_WatchCameraServer) error {
+	format := req.Format
+	if format == "" {
+		format = snapshotFormatJPEG
+	}
+
+	if format != snapshotFormatJPEG && format != snapshotFormatPNG {
+		return status.Error(codes.InvalidArgument, "format must be jpeg or png")
+	}
+
+	quality := int(req.Quality)
+	if quality == 0 {
+		quality = jpeg.DefaultQuality
+	}
+
+	opts, err := validateSnapshotOptions(snapshotOptions{Format: format, Width: int(req.Width), Quality: quality, Rotate: int(req.Rotate)})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	printer, err := s.findPrinter(req.PrinterId, true)
+	if err != nil {
+		return err
+	}
+
+	interval := defaultCameraFrameInterval
+	if s.context.Config.CameraFrameInterval > 0 {
+		interval = time.Duration(s.context.Config.CameraFrameInterval) * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastHash := ""
+	for {
+		if printer.backend() == nil {
+			return status.Error(codes.Unavailable, "printer is not connected")
+		}
+
+		frame, err := printer.CameraFrame()
+		if err != nil {
+			return status.Error(codes.Unavailable, err.Error())
+		}
+
+		if frame.Hash != lastHash {
+			if err := s.sendFrame(stream, frame, opts); err != nil {
+				return err
+			}
+
+			lastHash = frame.Hash
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stream.Context().Done():
+			return nil
+		case <-s.context.done:
+			return status.Error(codes.Unavailable, "makerbotd is shutting down")
+		}
+	}
+}
+
+func (s *grpcServer) sendFrame(stream makerbotdpb.Makerbotd_WatchCameraServer, frame *cachedFrame, opts snapshotOptions) error {
+	data, contentType, err := encodeSnapshot(frame.Frame, opts)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	pf := &makerbotdpb.CameraFrame{
+		Data:        data,
+		ContentType: contentType,
+		FetchedAt:   timestamppb.New(frame.FetchedAt),
+	}
+
+	if md := frame.Frame.Metadata; md != nil {
+		pf.Width, pf.Height = frameSize(md, opts)
+	}
+
+	return stream.Send(pf)
+}
+
+// frameSize is the size of a frame of size `md` once it is scaled and rotated
+// like encodeSnapshot does
+func frameSize(md *makerbot.CameraFrameMetadata, opts snapshotOptions) (width, height int32) {
+	width, height = int32(md.Width), int32(md.Height)
+
+	if opts.Width > 0 && width > 0 {
+		height = int32(int64(height) * int64(opts.Width) / int64(width))
+		if height < 1 {
+			height = 1
+		}
+		width = int32(opts.Width)
+	}
+
+	if opts.Rotate == 90 || opts.Rotate == 270 {
+		width, height = height, width
+	}
+
+	return width, height
+}
+
+func (s *grpcServer) WatchOperation(req *makerbotdpb.WatchOperationRequest, stream makerbotdpb.Makerbotd_WatchOperationServer) error {
+	updates, unsubscribe, ok := s.context.Operations.Subscribe(req.OperationId)
+	if !ok {
+		return status.Error(codes.NotFound, "operation not found")
+	}
+	defer unsubscribe()
+
+	for {
+		select {
+		case op, open := <-updates:
+			if !open {
+				return nil
+			}
+
+			if err := stream.Send(protoOperation(op)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.context.done:
+			return status.Error(codes.Unavailable, "makerbotd is shutting down")
+		}
+	}
+}
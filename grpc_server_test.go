@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"makerbotd/makerbotdpb"
+
+	"github.com/tjhorner/makerbot-rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPC serves the gRPC service for ctx over an in-memory connection and
+// returns a client for it
+func dialGRPC(t *testing.T, ctx *mbContext) makerbotdpb.MakerbotdClient {
+	lis := bufconn.Listen(1024 * 1024)
+
+	server := newGRPCServer(ctx)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(c context.Context, _ string) (net.Conn, error) { return lis.DialContext(c) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return makerbotdpb.NewMakerbotdClient(conn)
+}
+
+func TestGRPCPrint(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	_, fb := addFakePrinter(t, ctx, printerConfig{Name: "fake", ID: "1"})
+	loadSpool(t, ctx, "1", 100)
+
+	client := dialGRPC(t, ctx)
+
+	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Print(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stream.Send(&makerbotdpb.PrintRequest{Data: &makerbotdpb.PrintRequest_Header{Header: &makerbotdpb.PrintHeader{PrinterId: "fake", Filename: "part.makerbot"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The file needs more filament than is loaded, so a warning comes back
+	file := makerbotFile(t, 500)
+	for chunk := bytes.NewReader(file); chunk.Len() > 0; {
+		data := make([]byte, 1000)
+		n, _ := chunk.Read(data)
+
+		err = stream.Send(&makerbotdpb.PrintRequest{Data: &makerbotdpb.PrintRequest_Chunk{Chunk: data[:n]}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	op, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if op.Type != operationTypePrint || op.Printer != "1" || op.Transfer.GetTotal() != int64(len(file)) {
+		t.Errorf("got operation %+v, want a print of %d bytes on fake", op, len(file))
+	}
+
+	header, err := stream.Header()
+	if err != nil || len(header.Get("warning")) == 0 {
+		t.Errorf("got header %v (%v), want a warning about the filament", header, err)
+	}
+
+	waitFor(t, func() bool {
+		printed, ok := fb.Printed("part.makerbot")
+		return ok && bytes.Equal(printed, file)
+	})
+
+	waitFor(t, func() bool {
+		got, err := client.GetOperation(c, &makerbotdpb.GetOperationRequest{OperationId: op.Id})
+		return err == nil && got.Status == operationRunning && got.Step == "printing"
+	})
+}
+
+func TestGRPCWatchPrinter(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	_, fb := addFakePrinter(t, ctx, printerConfig{Name: "fake", ID: "1"})
+
+	client := dialGRPC(t, ctx)
+
+	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchPrinter(c, &makerbotdpb.WatchPrinterRequest{PrinterId: "fake"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The current state is sent right away
+	state, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state.Printer.GetId() != "1" || !state.Printer.GetConnected() || state.Job != nil {
+		t.Fatalf("got %+v, want the connected printer without a job", state)
+	}
+
+	fb.setState(func(m *makerbot.PrinterMetadata) {
+		m.CurrentProcess = &makerbot.PrinterProcess{ID: 1, Name: "PrintProcess", Step: "printing", Filename: "part.makerbot", Progress: 40}
+	})
+
+	for state.Job.GetStep() != "printing" {
+		state, err = stream.Recv()
+		if err != nil {
+			t.Fatalf("the stream ended before the job was sent: %v", err)
+		}
+	}
+
+	if state.Job.Filename != "part.makerbot" || state.Job.Progress != 40 {
+		t.Errorf("got job %+v, want part.makerbot at 40%%", state.Job)
+	}
+}
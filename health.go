@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	listenerSocket = "socket"
+	listenerTCP    = "tcp"
+	listenerGRPC   = "grpc"
+)
+
+type healthCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type healthResponse struct {
+	OK     bool          `json:"ok"`
+	Checks []healthCheck `json:"checks"`
+}
+
+// listenerStatus keeps track of which HTTP and gRPC listeners are currently serving
+type listenerStatus struct {
+	sync.Mutex
+	up map[string]bool
+}
+
+func (ls *listenerStatus) Set(name string, up bool) {
+	ls.Lock()
+	defer ls.Unlock()
+
+	if ls.up == nil {
+		ls.up = map[string]bool{}
+	}
+
+	ls.up[name] = up
+}
+
+func (ls *listenerStatus) Up(name string) bool {
+	ls.Lock()
+	defer ls.Unlock()
+
+	return ls.up[name]
+}
+
+func writeHealth(w http.ResponseWriter, checks []healthCheck) {
+	w.Header().Set("Content-Type", "application/json")
+
+	res := healthResponse{OK: true, Checks: checks}
+	for _, c := range checks {
+		if !c.OK {
+			res.OK = false
+		}
+	}
+
+	if !res.OK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.Encode(apiSuccess(res))
+}
+
+// healthz reports whether the process is alive and every configured listener is up
+func healthz(ctx *mbContext) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		checks := []healthCheck{{Name: "process", OK: true}}
+
+		if ctx.Config.ListenSocket {
+			checks = append(checks, healthCheck{
+				Name:   "listener:" + listenerSocket,
+				OK:     ctx.listeners.Up(listenerSocket),
+				Detail: ctx.Config.ListenSocketPath,
+			})
+		}
+
+		if ctx.Config.ListenTCP {
+			checks = append(checks, healthCheck{
+				Name:   "listener:" + listenerTCP,
+				OK:     ctx.listeners.Up(listenerTCP),
+				Detail: ctx.Config.ListenTCPAddress,
+			})
+		}
+
+		if ctx.Config.ListenGRPC {
+			checks = append(checks, healthCheck{
+				Name:   "listener:" + listenerGRPC,
+				OK:     ctx.listeners.Up(listenerGRPC),
+				Detail: ctx.Config.ListenGRPCAddress,
+			})
+		}
+
+		writeHealth(w, checks)
+	}
+}
+
+// readyz reports whether enough printers, or the specific printers listed in
+// ReadyPrinters, are connected
+func readyz(ctx *mbContext) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		checks := []healthCheck{}
+
+		connected := len(*ctx.Printers.ConnectedPrinters(""))
+		checks = append(checks, healthCheck{
+			Name:   "printers:connected",
+			OK:     connected >= ctx.Config.ReadyMinPrinters,
+			Detail: fmt.Sprintf("%d connected, %d required", connected, ctx.Config.ReadyMinPrinters),
+		})
+
+		for _, id := range ctx.Config.ReadyPrinters {
+			check := healthCheck{Name: "printer:" + id}
+
+			printer, ok := ctx.Printers.Find(id)
+			switch {
+			case !ok:
+				check.Detail = "not found"
+			case printer.backend() == nil:
+				check.Detail = "not connected"
+			default:
+				check.OK = true
+			}
+
+			checks = append(checks, check)
+		}
+
+		writeHealth(w, checks)
+	}
+}
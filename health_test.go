@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// checkHealth calls a health handler and returns its status code and checks by name
+func checkHealth(t *testing.T, handle httprouter.Handle) (int, map[string]healthCheck) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handle(rec, httptest.NewRequest("GET", "/", nil), nil)
+
+	var res struct {
+		Result healthResponse `json:"result"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := map[string]healthCheck{}
+	for _, c := range res.Result.Checks {
+		checks[c.Name] = c
+	}
+
+	if res.Result.OK != (rec.Code == http.StatusOK) {
+		t.Errorf("got %d with ok %v", rec.Code, res.Result.OK)
+	}
+
+	return rec.Code, checks
+}
+
+func TestHealthz(t *testing.T) {
+	ctx := newTestContext(t, &config{ListenTCP: true, ListenGRPC: true})
+
+	ctx.listeners.Set(listenerTCP, true)
+	ctx.listeners.Set(listenerGRPC, true)
+
+	if code, checks := checkHealth(t, healthz(ctx)); code != http.StatusOK || len(checks) != 3 {
+		t.Errorf("got %d with %v, want 200 with the process and two listeners", code, checks)
+	}
+
+	ctx.listeners.Set(listenerGRPC, false)
+
+	code, checks := checkHealth(t, healthz(ctx))
+	if code != http.StatusServiceUnavailable || checks["listener:grpc"].OK || !checks["listener:tcp"].OK {
+		t.Errorf("got %d with %v, want 503 with only the gRPC listener down", code, checks)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	ctx := newTestContext(t, &config{ReadyMinPrinters: 2})
+	addFakePrinter(t, ctx, printerConfig{Name: "one", ID: "1"})
+
+	code, checks := checkHealth(t, readyz(ctx))
+	if code != http.StatusServiceUnavailable || checks["printers:connected"].OK {
+		t.Errorf("got %d with %v, want 503 with one of two printers connected", code, checks)
+	}
+
+	_, fb := addFakePrinter(t, ctx, printerConfig{Name: "two", ID: "2"})
+
+	if code, _ := checkHealth(t, readyz(ctx)); code != http.StatusOK {
+		t.Errorf("got %d with two printers connected, want 200", code)
+	}
+
+	ctx.Config.ReadyMinPrinters = 0
+	ctx.Config.ReadyPrinters = []string{"one", "two", "three"}
+
+	code, checks = checkHealth(t, readyz(ctx))
+	if code != http.StatusServiceUnavailable || !checks["printer:one"].OK || !checks["printer:two"].OK || checks["printer:three"].Detail != "not found" {
+		t.Errorf("got %d with %v, want 503 with only printer three missing", code, checks)
+	}
+
+	ctx.Config.ReadyPrinters = []string{"one", "two"}
+	fb.Close()
+
+	waitFor(t, func() bool {
+		code, checks = checkHealth(t, readyz(ctx))
+		return checks["printer:two"].Detail == "not connected"
+	})
+
+	if code != http.StatusServiceUnavailable || !checks["printer:one"].OK {
+		t.Errorf("got %d with %v, want 503 with only printer two disconnected", code, checks)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const defaultHeartbeatTimeout = 60 * time.Second
+
+// touch records that the printer is still talking to us
+func (pc *printerConnection) touch() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.lastSeen = time.Now()
+}
+
+func (pc *printerConnection) sinceLastSeen() time.Duration {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	return time.Since(pc.lastSeen)
+}
+
+// ping pings the printer over `conn`, giving up after `timeout`
+func ping(conn printerBackend, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() { done <- conn.Ping() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return errors.New("ping timed out")
+	}
+}
+
+// heartbeat watches `conn` while it is the printer's active connection. A
+// printer that has sent nothing for HeartbeatTimeout is most likely behind a
+// half-open connection (e.g. after a Wi-Fi blip), so we force a reconnect
+// instead of letting every API call time out. Printers that have been quiet for
+// a whole interval are pinged first, if their backend can ping them over the
+// same connection their state comes in on, and a failed ping forces a
+// reconnect too.
+func (pc *printerConnection) heartbeat(conn printerBackend) {
+	if pc.context.Config.HeartbeatInterval <= 0 {
+		return
+	}
+
+	interval := time.Duration(pc.context.Config.HeartbeatInterval) * time.Second
+
+	timeout := defaultHeartbeatTimeout
+	if pc.context.Config.HeartbeatTimeout > 0 {
+		timeout = time.Duration(pc.context.Config.HeartbeatTimeout) * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pc.context.done:
+			return
+		case <-ticker.C:
+		}
+
+		pc.mu.Lock()
+		current := pc.connection == conn
+		pc.mu.Unlock()
+
+		if !current {
+			return
+		}
+
+		var err error
+
+		since := pc.sinceLastSeen()
+		switch {
+		case since >= timeout:
+			err = fmt.Errorf("nothing heard in %s", since.Round(time.Second))
+		case since < interval:
+			// The printer has been talking to us
+			continue
+		default:
+			err = ping(conn, timeout)
+			if err == errPingUnsupported {
+				continue
+			}
+		}
+
+		if err == nil {
+			pc.touch()
+			continue
+		}
+
+		_, machineName := pc.identity()
+		pc.context.Debugf("printerConnection: heartbeat failed for %s (%v), forcing disconnect\n", machineName, err)
+
+		conn.Close()
+		pc.handleDisconnect(conn)
+		return
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// The subtests run one after another: useFakeBackend swaps a global, and each
+// one waits on its own printer's heartbeat anyway
+func TestHeartbeat(t *testing.T) {
+	tests := []struct {
+		name      string
+		pingErr   error
+		quiet     time.Duration // quiet is how long ago the printer was last heard from
+		pinged    bool
+		connected bool
+	}{
+		{"idle printer that answers", nil, 0, true, true},
+		{"printer that doesn't answer", errors.New("connection reset"), 0, true, false},
+		{"printer that can't be pinged", errPingUnsupported, 0, true, true},
+		{"printer that can't be pinged and went quiet", errPingUnsupported, time.Hour, false, false},
+		{"printer that answers but sent nothing for too long", nil, time.Hour, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := newTestContext(t, &config{HeartbeatInterval: 1, HeartbeatTimeout: 5})
+			pc, fb := addFakePrinter(t, ctx, printerConfig{ID: "1"})
+
+			fb.mu.Lock()
+			fb.pingErr = test.pingErr
+			fb.mu.Unlock()
+
+			pc.mu.Lock()
+			pc.lastSeen = pc.lastSeen.Add(-test.quiet)
+			pc.mu.Unlock()
+
+			pings := func() int {
+				fb.mu.Lock()
+				defer fb.mu.Unlock()
+
+				return fb.pings
+			}
+
+			if test.connected {
+				// Once the printer has been pinged, the heartbeat has made up its mind
+				waitFor(t, func() bool { return pings() > 0 })
+			} else {
+				waitFor(t, func() bool { return pc.backend() == nil })
+			}
+
+			if connected := pc.backend() != nil; connected != test.connected {
+				t.Errorf("connected = %v, want %v", connected, test.connected)
+			}
+
+			if pinged := pings() > 0; pinged != test.pinged {
+				t.Errorf("pinged = %v, want %v", pinged, test.pinged)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+const (
+	// maxProgressSamples caps how many progress samples are kept for a job. Once
+	// there are more, every other one is dropped.
+	maxProgressSamples = 1000
+	// progressRateWindow is how far back samples are used to work out how fast a
+	// job is progressing
+	progressRateWindow = 15 * time.Minute
+)
+
+// progressSample is how far along a job was at some point
+type progressSample struct {
+	Time        time.Time `json:"time"`
+	Step        string    `json:"step"`
+	Progress    int       `json:"progress"`
+	ElapsedTime int       `json:"elapsed_time"`
+}
+
+// jobHistory is the progress of the printer's current process over time
+type jobHistory struct {
+	ProcessID        int
+	StartedAt        time.Time
+	ExpectedDuration float64 // ExpectedDuration comes from the .makerbot file, in seconds. 0 if unknown.
+	Samples          []progressSample
+}
+
+// jobProgress is the printer's current process along with its progress history
+type jobProgress struct {
+	*makerbot.PrinterProcess
+	StartedAt *time.Time       `json:"started_at"`
+	ETA       *time.Time       `json:"eta"`
+	History   []progressSample `json:"history"`
+}
+
+// setExpectedDuration remembers how long the print that was just sent should take, in seconds
+func (pc *printerConnection) setExpectedDuration(seconds float64) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.expectedDuration = seconds
+}
+
+// sampleProgress records the progress of the printer's current process
+func (pc *printerConnection) sampleProgress(md *makerbot.PrinterMetadata) {
+	if md == nil || md.CurrentProcess == nil {
+		return
+	}
+
+	proc := md.CurrentProcess
+	now := time.Now()
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.job == nil || pc.job.ProcessID != proc.ID {
+		pc.job = &jobHistory{
+			ProcessID: proc.ID,
+			// makerbotd might have started after the job did
+			StartedAt: now.Add(-time.Duration(proc.ElapsedTime) * time.Second),
+		}
+	}
+
+	job := pc.job
+
+	// The print is sent before its process shows up, so pick up its duration once it does
+	if job.ExpectedDuration == 0 && pc.expectedDuration > 0 && proc.Name == "PrintProcess" {
+		job.ExpectedDuration = pc.expectedDuration
+		pc.expectedDuration = 0
+	}
+
+	if n := len(job.Samples); n > 0 && job.Samples[n-1].Progress == proc.Progress && job.Samples[n-1].Step == proc.Step {
+		return
+	}
+
+	job.Samples = append(job.Samples, progressSample{
+		Time:        now,
+		Step:        proc.Step,
+		Progress:    proc.Progress,
+		ElapsedTime: proc.ElapsedTime,
+	})
+
+	if len(job.Samples) > maxProgressSamples {
+		kept := job.Samples[:0]
+		for i, s := range job.Samples {
+			if i%2 == 0 || i == len(job.Samples)-1 {
+				kept = append(kept, s)
+			}
+		}
+		job.Samples = kept
+	}
+}
+
+// remaining estimates how many seconds are left in the job. The rate the job
+// has been progressing at and how long the .makerbot file says it takes are
+// blended, trusting the rate more the further along the job is. ok is false if
+// there is nothing to go on.
+func (job *jobHistory) remaining(proc *makerbot.PrinterProcess) (seconds float64, ok bool) {
+	left := float64(100-proc.Progress) / 100
+
+	var fromRate float64
+	haveRate := false
+
+	var first, last *progressSample
+	for i := range job.Samples {
+		s := &job.Samples[i]
+		if s.Step != "printing" || time.Since(s.Time) > progressRateWindow {
+			continue
+		}
+
+		if first == nil {
+			first = s
+		}
+		last = s
+	}
+
+	if first != nil && last.Progress > first.Progress {
+		rate := float64(last.Progress-first.Progress) / 100 / last.Time.Sub(first.Time).Seconds()
+		fromRate = left / rate
+		haveRate = true
+	}
+
+	if job.ExpectedDuration <= 0 {
+		return fromRate, haveRate
+	}
+
+	fromFile := job.ExpectedDuration * left
+	if !haveRate {
+		return fromFile, true
+	}
+
+	trust := float64(proc.Progress) / 100
+	return trust*fromRate + (1-trust)*fromFile, true
+}
+
+// JobProgress returns the printer's current process along with when it started,
+// when it should be done and how it has progressed so far
+func (pc *printerConnection) JobProgress() *jobProgress {
+	proc := pc.currentProcess()
+	if proc == nil {
+		return nil
+	}
+
+	jp := &jobProgress{PrinterProcess: proc, History: []progressSample{}}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	job := pc.job
+	if job == nil || job.ProcessID != proc.ID {
+		return jp
+	}
+
+	startedAt := job.StartedAt
+	jp.StartedAt = &startedAt
+	jp.History = append(jp.History, job.Samples...)
+
+	if proc.Complete {
+		return jp
+	}
+
+	if seconds, ok := job.remaining(proc); ok {
+		eta := time.Now().Add(time.Duration(seconds * float64(time.Second))).Round(time.Second)
+		jp.ETA = &eta
+	}
+
+	return jp
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+func TestJobRemaining(t *testing.T) {
+	now := time.Now()
+
+	// printedAt is a sample from `ago` before now
+	printedAt := func(ago time.Duration, progress int) progressSample {
+		return progressSample{Time: now.Add(-ago), Step: "printing", Progress: progress}
+	}
+
+	tests := []struct {
+		name     string
+		job      jobHistory
+		progress int
+		want     float64
+		ok       bool
+	}{
+		{"nothing to go on", jobHistory{}, 20, 0, false},
+		{"from the file", jobHistory{ExpectedDuration: 3600}, 25, 2700, true},
+		{"from the rate", jobHistory{Samples: []progressSample{printedAt(100*time.Second, 10), printedAt(0, 20)}}, 20, 800, true},
+		{
+			"blended, trusting the rate by how far along the job is",
+			jobHistory{ExpectedDuration: 3600, Samples: []progressSample{printedAt(100*time.Second, 10), printedAt(0, 20)}},
+			20, 0.2*800 + 0.8*2880, true,
+		},
+		{"0% with the file", jobHistory{ExpectedDuration: 3600, Samples: []progressSample{printedAt(time.Minute, 0), printedAt(0, 0)}}, 0, 3600, true},
+		{"0% without the file", jobHistory{Samples: []progressSample{printedAt(time.Minute, 0), printedAt(0, 0)}}, 0, 0, false},
+		{
+			"samples outside the window are ignored",
+			jobHistory{Samples: []progressSample{printedAt(progressRateWindow+time.Minute, 0), printedAt(time.Second, 20)}},
+			20, 0, false,
+		},
+		{
+			"samples that aren't printing are ignored",
+			jobHistory{Samples: []progressSample{{Time: now.Add(-time.Minute), Step: "heating", Progress: 0}, printedAt(0, 20)}},
+			20, 0, false,
+		},
+		{"finished", jobHistory{ExpectedDuration: 3600, Samples: []progressSample{printedAt(100*time.Second, 90), printedAt(0, 100)}}, 100, 0, true},
+	}
+
+	for _, test := range tests {
+		got, ok := test.job.remaining(&makerbot.PrinterProcess{Step: "printing", Progress: test.progress})
+		if ok != test.ok || math.Abs(got-test.want) > 1 {
+			t.Errorf("%s: got %.0f, %v, want %.0f, %v", test.name, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestJobProgress(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	pc, fb := addFakePrinter(t, ctx, printerConfig{Name: "fake"})
+
+	if pc.JobProgress() != nil {
+		t.Fatal("an idle printer has a job")
+	}
+
+	pc.setExpectedDuration(3600)
+
+	fb.setState(func(m *makerbot.PrinterMetadata) {
+		m.CurrentProcess = &makerbot.PrinterProcess{ID: 7, Name: "PrintProcess", Step: "printing", Progress: 50, ElapsedTime: 600}
+	})
+
+	jp := pc.JobProgress()
+	if jp == nil || jp.StartedAt == nil || jp.ETA == nil || len(jp.History) != 1 {
+		t.Fatalf("got %+v, want a job with a start, an ETA and one sample", jp)
+	}
+
+	if since := time.Since(*jp.StartedAt); since < 599*time.Second || since > 601*time.Second {
+		t.Errorf("job started %v ago, want 10m", since)
+	}
+
+	// Halfway through a print that takes an hour
+	if until := time.Until(*jp.ETA); until < 29*time.Minute || until > 31*time.Minute {
+		t.Errorf("job ends in %v, want 30m", until)
+	}
+
+	fb.setState(func(m *makerbot.PrinterMetadata) {
+		m.CurrentProcess = &makerbot.PrinterProcess{ID: 7, Name: "PrintProcess", Step: "completed", Progress: 100, Complete: true}
+	})
+
+	jp = pc.JobProgress()
+	if jp == nil || jp.ETA != nil || len(jp.History) != 2 {
+		t.Errorf("got %+v, want a finished job with no ETA and two samples", jp)
+	}
+}
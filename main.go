@@ -1,19 +1,44 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"os/user"
+	"path/filepath"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
+
+	"google.golang.org/grpc"
 )
 
+// shutdownTimeout is how long in-flight requests get to finish when shutting down
+const shutdownTimeout = 30 * time.Second
+
 type mbContext struct {
-	Printers *printerConnections
-	Config   *config
+	Printers   *printerConnections
+	Config     *config
+	Spools     *spoolInventory
+	Operations *operations
+	Metrics    *metrics
+	listeners  listenerStatus
+	done       chan struct{} // done is closed when makerbotd is shutting down
+}
+
+// ShuttingDown returns true once makerbotd has started shutting down
+func (ctx *mbContext) ShuttingDown() bool {
+	select {
+	case <-ctx.done:
+		return true
+	default:
+		return false
+	}
 }
 
 func (ctx *mbContext) Debugln(v ...interface{}) {
@@ -42,23 +67,43 @@ func main() {
 	forceListen := flag.Bool("force-listen", false, "force listen on unix socket if it is in use")
 	flag.Parse()
 
+	switch flag.Arg(0) {
+	case "discover":
+		discoverCommand()
+		return
+	case "simulate":
+		simulateCommand(flag.Args()[1:])
+		return
+	}
+
 	conf, err := loadConfig(*confPath)
 	if err != nil {
 		panic(err)
 	}
 
-	ctx := mbContext{Config: conf}
+	ctx := mbContext{Config: conf, Operations: newOperations(), Metrics: newMetrics(), done: make(chan struct{})}
 
-	printers := printerConnections{}
+	if conf.SpoolsPath == "" {
+		conf.SpoolsPath = filepath.Join(filepath.Dir(*confPath), "spools.json")
+	}
+
+	ctx.Spools, err = loadSpoolInventory(conf.SpoolsPath)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx.Printers = &printerConnections{}
 
 	// Set up printer connections
 	for _, pc := range conf.Printers {
 		conn := newPrinterConnection(&ctx, pc)
 		go conn.Connect()
-		printers = append(printers, conn)
+		ctx.Printers.Add(conn)
 	}
 
-	ctx.Printers = &printers
+	if conf.AutoAddPrinters {
+		go ctx.autoAddPrinters()
+	}
 
 	router := getRouter(&ctx)
 
@@ -67,6 +112,12 @@ func main() {
 		ReadTimeout: 5 * time.Minute,
 	}
 
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	// Listener goroutines report here instead of panicking so we still get to clean up
+	failed := make(chan error, 3)
+
 	var wg sync.WaitGroup
 
 	if conf.ListenSocket {
@@ -76,42 +127,137 @@ func main() {
 
 		wg.Add(1)
 		go func() {
+			defer wg.Done()
+
 			sock, err := net.Listen("unix", conf.ListenSocketPath)
 			if err != nil {
-				panic(err)
+				failed <- err
+				return
 			}
 
 			defer sock.Close()
 			defer os.Remove(conf.ListenSocketPath)
 
+			ctx.listeners.Set(listenerSocket, true)
+			defer ctx.listeners.Set(listenerSocket, false)
+
 			log.Printf("HTTP server listening on UNIX domain socket (force=%v): %s", *forceListen, conf.ListenSocketPath)
 
 			err = server.Serve(sock)
-			if err != nil {
-				panic(err)
+			if err != http.ErrServerClosed {
+				failed <- err
 			}
-			wg.Done()
 		}()
 	}
 
 	if conf.ListenTCP {
 		wg.Add(1)
 		go func() {
+			defer wg.Done()
+
 			conn, err := net.Listen("tcp", conf.ListenTCPAddress)
 			if err != nil {
-				panic(err)
+				failed <- err
+				return
 			}
 			defer conn.Close()
 
+			ctx.listeners.Set(listenerTCP, true)
+			defer ctx.listeners.Set(listenerTCP, false)
+
 			log.Printf("HTTP server listening on TCP address: %s", conf.ListenTCPAddress)
 
 			err = server.Serve(conn)
+			if err != http.ErrServerClosed {
+				failed <- err
+			}
+		}()
+	}
+
+	grpcServer := newGRPCServer(&ctx)
+
+	if conf.ListenGRPC {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			network, address := "tcp", conf.ListenGRPCAddress
+			if strings.HasPrefix(address, "unix:") {
+				network, address = "unix", strings.TrimPrefix(address, "unix:")
+
+				if *forceListen {
+					os.Remove(address)
+				}
+			}
+
+			lis, err := net.Listen(network, address)
 			if err != nil {
-				panic(err)
+				failed <- err
+				return
+			}
+
+			if network == "unix" {
+				defer os.Remove(address)
+			}
+
+			ctx.listeners.Set(listenerGRPC, true)
+			defer ctx.listeners.Set(listenerGRPC, false)
+
+			log.Printf("gRPC server listening on %s address: %s", network, address)
+
+			// Serve closes the listener when it returns
+			err = grpcServer.Serve(lis)
+			if err != nil {
+				failed <- err
 			}
-			wg.Done()
 		}()
 	}
 
+	exitCode := 0
+
+	select {
+	case sig := <-sigs:
+		log.Printf("Received %v, shutting down...", sig)
+	case err := <-failed:
+		log.Printf("Server failed, shutting down: %v", err)
+		exitCode = 1
+	}
+
+	ctx.Shutdown(&server, grpcServer)
 	wg.Wait()
+
+	os.Exit(exitCode)
+}
+
+// Shutdown stops reconnecting to printers, drains in-flight requests and then
+// closes every printer connection
+func (ctx *mbContext) Shutdown(server *http.Server, grpcServer *grpc.Server) {
+	sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Streams, long polls and streaming calls only end once makerbotd is
+	// shutting down, so this has to happen first or they would hold up draining
+	// until the timeout
+	close(ctx.done)
+
+	// New gRPC calls are refused right away, but running ones get to finish
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+
+	err := server.Shutdown(sctx)
+	if err != nil {
+		log.Printf("Could not drain HTTP requests: %v", err)
+	}
+
+	select {
+	case <-grpcStopped:
+	case <-sctx.Done():
+		log.Printf("Could not drain gRPC calls: %v", sctx.Err())
+		grpcServer.Stop()
+	}
+
+	ctx.Printers.CloseAll()
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestShutdownEndsLongPolls(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	pc, _ := addFakePrinter(t, ctx, printerConfig{Name: "fake", ID: "1"})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active := make(chan struct{}, 1)
+	server := http.Server{
+		Handler: getRouter(ctx),
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			if state == http.StateActive {
+				active <- struct{}{}
+			}
+		},
+	}
+	go server.Serve(lis)
+
+	// A long poll that would otherwise wait two minutes for the printer's state to change
+	rev, _ := pc.Revision()
+
+	polled := make(chan error, 1)
+	go func() {
+		res, err := http.Get("http://" + lis.Addr().String() + "/api/v1/printers/fake?wait=2m&since=" + strconv.FormatUint(rev, 10))
+		if err == nil {
+			res.Body.Close()
+		}
+
+		polled <- err
+	}()
+
+	<-active
+
+	start := time.Now()
+	ctx.Shutdown(&server, newGRPCServer(ctx))
+
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("shutting down took %s with a long poll running", took)
+	}
+
+	if err := <-polled; err != nil {
+		t.Errorf("the long poll failed instead of being answered: %v", err)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMakerbotAuthPort is the port MakerBot printers serve their authentication endpoint on
+	defaultMakerbotAuthPort = "80"
+
+	// makerbotAuthClientID and makerbotAuthClientSecret are what MakerBot's own
+	// software identifies itself as
+	makerbotAuthClientID     = "MakerWare"
+	makerbotAuthClientSecret = "secret"
+
+	// makerbotAuthAttempts is how many times we ask the printer whether the
+	// authentication request was accepted before giving up
+	makerbotAuthAttempts = 30
+)
+
+var (
+	errRPCClosed       = errors.New("the printer's JSON-RPC connection was closed")
+	errRPCNotConnected = errors.New("the printer's JSON-RPC connection dropped")
+)
+
+type rpcOutcome struct {
+	result json.RawMessage
+	err    error
+}
+
+// makerbotRPC is a JSON-RPC connection of our own to a printer on the local
+// network, for calls that makerbot-rpc doesn't expose. It connects and
+// authenticates the first time it is used, and again after the connection drops.
+type makerbotRPC struct {
+	authMu     sync.Mutex // authMu makes sure only one caller authenticates at a time
+	mu         sync.Mutex
+	ip         string
+	port       string
+	authPort   string // authPort is the port the printer's authentication endpoint is on
+	username   string // username and token are the Thingiverse account the printer is authorized with
+	token      string
+	timeout    time.Duration
+	conn       net.Conn
+	authorized bool // authorized is true once conn has been authorized
+	nextID     int
+	pending    map[int]chan rpcOutcome
+	closed     bool
+}
+
+func newMakerbotRPC(ip, port, authPort, username, token string, timeout time.Duration) *makerbotRPC {
+	if authPort == "" {
+		authPort = defaultMakerbotAuthPort
+	}
+
+	return &makerbotRPC{ip: ip, port: port, authPort: authPort, username: username, token: token, timeout: timeout, pending: map[int]chan rpcOutcome{}}
+}
+
+// auth makes a request to the printer's authentication endpoint
+func (c *makerbotRPC) auth(query url.Values, result interface{}) error {
+	query.Set("client_id", makerbotAuthClientID)
+	query.Set("client_secret", makerbotAuthClientSecret)
+
+	client := http.Client{Timeout: c.timeout}
+
+	res, err := client.Get("http://" + net.JoinHostPort(c.ip, c.authPort) + "/auth?" + query.Encode())
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("printer authentication returned %s", res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+// accessToken gets a JSON-RPC access token from the printer for our Thingiverse account
+func (c *makerbotRPC) accessToken() (string, error) {
+	var code struct {
+		AnswerCode string `json:"answer_code"`
+	}
+
+	err := c.auth(url.Values{"response_type": {"code"}, "username": {c.username}, "thingiverse_token": {c.token}}, &code)
+	if err != nil {
+		return "", err
+	}
+
+	var answer struct {
+		Answer string `json:"answer"`
+		Code   string `json:"code"`
+	}
+
+	for i := 0; answer.Answer != "accepted"; i++ {
+		if i == makerbotAuthAttempts {
+			return "", errors.New("the printer did not accept the authentication request")
+		}
+
+		if i > 0 {
+			time.Sleep(time.Second)
+		}
+
+		err = c.auth(url.Values{"response_type": {"answer"}, "answer_code": {code.AnswerCode}}, &answer)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	err = c.auth(url.Values{"response_type": {"token"}, "auth_code": {answer.Code}, "context": {"jsonrpc"}}, &token)
+	if err != nil {
+		return "", err
+	}
+
+	if token.AccessToken == "" {
+		return "", errors.New("the printer did not hand out an access token")
+	}
+
+	return token.AccessToken, nil
+}
+
+// connect dials the printer and starts reading responses. It must be called with c locked.
+func (c *makerbotRPC) connect() (net.Conn, error) {
+	if c.closed {
+		return nil, errRPCClosed
+	}
+
+	if c.conn != nil {
+		c.forget(errRPCNotConnected)
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(c.ip, c.port), c.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c.conn = conn
+	c.authorized = false
+	go c.read(conn)
+
+	return conn, nil
+}
+
+// read delivers responses on `conn` to whoever is waiting for them until the
+// connection drops. Notifications are left to makerbot-rpc's own connection.
+func (c *makerbotRPC) read(conn net.Conn) {
+	dec := json.NewDecoder(conn)
+
+	for {
+		var reply rpcReply
+		err := dec.Decode(&reply)
+		if err != nil {
+			c.drop(conn, err)
+			return
+		}
+
+		if reply.ID == nil {
+			continue
+		}
+
+		c.mu.Lock()
+		ch, ok := c.pending[*reply.ID]
+		delete(c.pending, *reply.ID)
+		c.mu.Unlock()
+
+		if !ok {
+			continue
+		}
+
+		if reply.Error != nil {
+			ch <- rpcOutcome{err: reply.Error}
+		} else {
+			ch <- rpcOutcome{result: reply.Result}
+		}
+	}
+}
+
+// drop closes `conn`, and forgets it if it is the current connection
+func (c *makerbotRPC) drop(conn net.Conn, err error) {
+	conn.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == conn {
+		c.forget(err)
+	}
+}
+
+// forget closes the current connection and fails every call still waiting on
+// it with `err`. It must be called with c locked.
+func (c *makerbotRPC) forget(err error) {
+	c.conn.Close()
+	c.conn = nil
+	c.authorized = false
+
+	for id, ch := range c.pending {
+		ch <- rpcOutcome{err: err}
+		delete(c.pending, id)
+	}
+}
+
+// call makes a JSON-RPC call on `conn`
+func (c *makerbotRPC) call(conn net.Conn, method string, params json.RawMessage) (json.RawMessage, error) {
+	c.mu.Lock()
+	if c.conn != conn {
+		c.mu.Unlock()
+		return nil, errRPCNotConnected
+	}
+
+	id := c.nextID
+	c.nextID++
+
+	ch := make(chan rpcOutcome, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	req, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	_, err = conn.Write(req)
+	if err != nil {
+		c.drop(conn, err)
+		return nil, err
+	}
+
+	select {
+	case out := <-ch:
+		return out.result, out.err
+	case <-time.After(c.timeout):
+		// The connection is in an unknown state, so start over next time
+		err = fmt.Errorf("%s timed out", method)
+		c.drop(conn, err)
+		return nil, err
+	}
+}
+
+// authorize makes sure there is an authorized connection to the printer,
+// connecting and authenticating if there isn't, and returns it
+func (c *makerbotRPC) authorize() (net.Conn, error) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	c.mu.Lock()
+	conn, authorized := c.conn, c.authorized
+	c.mu.Unlock()
+
+	if conn != nil && authorized {
+		return conn, nil
+	}
+
+	token, err := c.accessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	conn, err = c.connect()
+	c.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	params, _ := json.Marshal(map[string]string{"access_token": token})
+
+	_, err = c.call(conn, "authorize", params)
+	if err != nil {
+		c.drop(conn, err)
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.authorized = c.conn == conn
+	c.mu.Unlock()
+
+	return conn, nil
+}
+
+// Call makes a JSON-RPC call to the printer, connecting and authenticating first
+// if needed
+func (c *makerbotRPC) Call(method string, params json.RawMessage) (json.RawMessage, error) {
+	conn, err := c.authorize()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.call(conn, method, params)
+}
+
+// Close closes the connection. The makerbotRPC can't be used afterwards.
+func (c *makerbotRPC) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	c.drop(conn, errRPCClosed)
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// simulatorBackend returns a makerbotBackend for the simulator at `addr`, whose
+// authentication endpoint is at `authURL`
+func simulatorBackend(t *testing.T, addr, authURL string) *makerbotBackend {
+	u, _ := url.Parse(authURL)
+
+	host, port, _ := net.SplitHostPort(addr)
+	b := newMakerbotBackend(newTestContext(t, &config{}), printerConfig{ConnectionType: connectionTypeLocal, IP: host, Port: port, AuthPort: u.Port()})
+	t.Cleanup(func() { b.Close() })
+
+	return b.(*makerbotBackend)
+}
+
+func TestMakerbotBackendCall(t *testing.T) {
+	sp, addr, authURL := startSimulator(t)
+	b := simulatorBackend(t, addr, authURL)
+
+	result, err := b.Call("get_system_information", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var info struct {
+		Info struct {
+			Serial string `json:"iserial"`
+		} `json:"info"`
+	}
+	json.Unmarshal(result, &info)
+
+	if info.Info.Serial != "SIM0001" {
+		t.Errorf("unexpected system information %s", result)
+	}
+
+	_, err = b.Call("load_filament", json.RawMessage(`{"tool_index": 0}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = b.Call("print", json.RawMessage(`{"filepath": "part.makerbot"}`))
+	var rerr *rpcError
+	if !errors.As(err, &rerr) || rerr.Message != "printer is busy" {
+		t.Errorf("Call() = %v, want the printer's error", err)
+	}
+
+	_, err = b.Call("request_camera_frame", nil)
+	if err == nil {
+		t.Error("expected a call followed by raw data to be refused")
+	}
+
+	// Drop every connection on the simulator's side. The next call has to
+	// connect and authorize again.
+	sp.Lock()
+	for c := range sp.clients {
+		c.conn.Close()
+	}
+	sp.Unlock()
+
+	waitFor(t, func() bool {
+		b.rpc.mu.Lock()
+		defer b.rpc.mu.Unlock()
+
+		return b.rpc.conn == nil
+	})
+
+	_, err = b.Call("get_system_information", nil)
+	if err != nil {
+		t.Fatalf("call after reconnecting: %v", err)
+	}
+
+	// Raw calls don't go over the connection state comes in on, so they can't
+	// be used to ping
+	err = b.Ping()
+	if err != errPingUnsupported {
+		t.Errorf("Ping() = %v, want %v", err, errPingUnsupported)
+	}
+
+	b.Close()
+
+	_, err = b.Call("get_system_information", nil)
+	if err != errRPCClosed {
+		t.Errorf("Call() after Close() = %v, want %v", err, errRPCClosed)
+	}
+}
+
+func TestMakerbotBackendCallTimeout(t *testing.T) {
+	_, _, authURL := startSimulator(t)
+
+	// A printer that accepts connections but never answers
+	sock, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sock.Close()
+
+	go func() {
+		for {
+			conn, err := sock.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	b := simulatorBackend(t, sock.Addr().String(), authURL)
+	b.rpc.timeout = 100 * time.Millisecond
+
+	_, err = b.Call("get_system_information", nil)
+	if err == nil {
+		t.Fatal("expected the call to time out")
+	}
+}
+
+func TestMakerbotBackendCallRemote(t *testing.T) {
+	b := newMakerbotBackend(newTestContext(t, &config{}), printerConfig{ConnectionType: connectionTypeRemote, ID: "1"})
+
+	_, err := b.(rpcBackend).Call("get_system_information", nil)
+	if err != errRawRPCUnsupported {
+		t.Errorf("Call() = %v, want %v", err, errRawRPCUnsupported)
+	}
+}
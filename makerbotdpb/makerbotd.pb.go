@@ -0,0 +1,1908 @@
+// The makerbotd gRPC service. Regenerate the Go stubs after changing this file:
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//     makerbotdpb/makerbotd.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: makerbotdpb/makerbotd.proto
+
+package makerbotdpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Printer is a configured printer
+type Printer struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name           string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Serial         string                 `protobuf:"bytes,3,opt,name=serial,proto3" json:"serial,omitempty"`
+	MachineName    string                 `protobuf:"bytes,4,opt,name=machine_name,json=machineName,proto3" json:"machine_name,omitempty"`
+	ConnectionType string                 `protobuf:"bytes,5,opt,name=connection_type,json=connectionType,proto3" json:"connection_type,omitempty"`
+	Tags           []string               `protobuf:"bytes,6,rep,name=tags,proto3" json:"tags,omitempty"`
+	Connected      bool                   `protobuf:"varint,7,opt,name=connected,proto3" json:"connected,omitempty"`
+	Revision       uint64                 `protobuf:"varint,8,opt,name=revision,proto3" json:"revision,omitempty"`
+	// info is what the printer reports about itself while connected
+	Info          *PrinterInfo `protobuf:"bytes,9,opt,name=info,proto3" json:"info,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Printer) Reset() {
+	*x = Printer{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Printer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Printer) ProtoMessage() {}
+
+func (x *Printer) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Printer.ProtoReflect.Descriptor instead.
+func (*Printer) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Printer) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Printer) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Printer) GetSerial() string {
+	if x != nil {
+		return x.Serial
+	}
+	return ""
+}
+
+func (x *Printer) GetMachineName() string {
+	if x != nil {
+		return x.MachineName
+	}
+	return ""
+}
+
+func (x *Printer) GetConnectionType() string {
+	if x != nil {
+		return x.ConnectionType
+	}
+	return ""
+}
+
+func (x *Printer) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Printer) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+func (x *Printer) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *Printer) GetInfo() *PrinterInfo {
+	if x != nil {
+		return x.Info
+	}
+	return nil
+}
+
+// PrinterInfo is what a connected printer reports about itself
+type PrinterInfo struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	MachineType     string                 `protobuf:"bytes,1,opt,name=machine_type,json=machineType,proto3" json:"machine_type,omitempty"`
+	MachineName     string                 `protobuf:"bytes,2,opt,name=machine_name,json=machineName,proto3" json:"machine_name,omitempty"`
+	BotType         string                 `protobuf:"bytes,3,opt,name=bot_type,json=botType,proto3" json:"bot_type,omitempty"`
+	Ip              string                 `protobuf:"bytes,4,opt,name=ip,proto3" json:"ip,omitempty"`
+	FirmwareVersion string                 `protobuf:"bytes,5,opt,name=firmware_version,json=firmwareVersion,proto3" json:"firmware_version,omitempty"`
+	ApiVersion      string                 `protobuf:"bytes,6,opt,name=api_version,json=apiVersion,proto3" json:"api_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PrinterInfo) Reset() {
+	*x = PrinterInfo{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrinterInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrinterInfo) ProtoMessage() {}
+
+func (x *PrinterInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrinterInfo.ProtoReflect.Descriptor instead.
+func (*PrinterInfo) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PrinterInfo) GetMachineType() string {
+	if x != nil {
+		return x.MachineType
+	}
+	return ""
+}
+
+func (x *PrinterInfo) GetMachineName() string {
+	if x != nil {
+		return x.MachineName
+	}
+	return ""
+}
+
+func (x *PrinterInfo) GetBotType() string {
+	if x != nil {
+		return x.BotType
+	}
+	return ""
+}
+
+func (x *PrinterInfo) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *PrinterInfo) GetFirmwareVersion() string {
+	if x != nil {
+		return x.FirmwareVersion
+	}
+	return ""
+}
+
+func (x *PrinterInfo) GetApiVersion() string {
+	if x != nil {
+		return x.ApiVersion
+	}
+	return ""
+}
+
+// Job is the printer's current process
+type Job struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name        string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Step        string                 `protobuf:"bytes,3,opt,name=step,proto3" json:"step,omitempty"`
+	Progress    int32                  `protobuf:"varint,4,opt,name=progress,proto3" json:"progress,omitempty"`
+	Reason      string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`
+	Cancellable bool                   `protobuf:"varint,6,opt,name=cancellable,proto3" json:"cancellable,omitempty"`
+	Cancelled   bool                   `protobuf:"varint,7,opt,name=cancelled,proto3" json:"cancelled,omitempty"`
+	Complete    bool                   `protobuf:"varint,8,opt,name=complete,proto3" json:"complete,omitempty"`
+	// methods are the actions the job accepts right now
+	Methods     []string               `protobuf:"bytes,9,rep,name=methods,proto3" json:"methods,omitempty"`
+	ElapsedTime int32                  `protobuf:"varint,10,opt,name=elapsed_time,json=elapsedTime,proto3" json:"elapsed_time,omitempty"`
+	Filename    string                 `protobuf:"bytes,11,opt,name=filename,proto3" json:"filename,omitempty"`
+	StartedAt   *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	// eta is when the job should be done, if it can be estimated
+	Eta           *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=eta,proto3" json:"eta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Job) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Job) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Job) GetStep() string {
+	if x != nil {
+		return x.Step
+	}
+	return ""
+}
+
+func (x *Job) GetProgress() int32 {
+	if x != nil {
+		return x.Progress
+	}
+	return 0
+}
+
+func (x *Job) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Job) GetCancellable() bool {
+	if x != nil {
+		return x.Cancellable
+	}
+	return false
+}
+
+func (x *Job) GetCancelled() bool {
+	if x != nil {
+		return x.Cancelled
+	}
+	return false
+}
+
+func (x *Job) GetComplete() bool {
+	if x != nil {
+		return x.Complete
+	}
+	return false
+}
+
+func (x *Job) GetMethods() []string {
+	if x != nil {
+		return x.Methods
+	}
+	return nil
+}
+
+func (x *Job) GetElapsedTime() int32 {
+	if x != nil {
+		return x.ElapsedTime
+	}
+	return 0
+}
+
+func (x *Job) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *Job) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Job) GetEta() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Eta
+	}
+	return nil
+}
+
+// Tool is one of a printer's toolheads
+type Tool struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Index              int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	CurrentTemperature int32                  `protobuf:"varint,2,opt,name=current_temperature,json=currentTemperature,proto3" json:"current_temperature,omitempty"`
+	TargetTemperature  int32                  `protobuf:"varint,3,opt,name=target_temperature,json=targetTemperature,proto3" json:"target_temperature,omitempty"`
+	FilamentPresent    bool                   `protobuf:"varint,4,opt,name=filament_present,json=filamentPresent,proto3" json:"filament_present,omitempty"`
+	ToolPresent        bool                   `protobuf:"varint,5,opt,name=tool_present,json=toolPresent,proto3" json:"tool_present,omitempty"`
+	// spool_id is the spool loaded into the tool, if makerbotd knows about it
+	SpoolId       string `protobuf:"bytes,6,opt,name=spool_id,json=spoolId,proto3" json:"spool_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Tool) Reset() {
+	*x = Tool{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Tool) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tool) ProtoMessage() {}
+
+func (x *Tool) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tool.ProtoReflect.Descriptor instead.
+func (*Tool) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Tool) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Tool) GetCurrentTemperature() int32 {
+	if x != nil {
+		return x.CurrentTemperature
+	}
+	return 0
+}
+
+func (x *Tool) GetTargetTemperature() int32 {
+	if x != nil {
+		return x.TargetTemperature
+	}
+	return 0
+}
+
+func (x *Tool) GetFilamentPresent() bool {
+	if x != nil {
+		return x.FilamentPresent
+	}
+	return false
+}
+
+func (x *Tool) GetToolPresent() bool {
+	if x != nil {
+		return x.ToolPresent
+	}
+	return false
+}
+
+func (x *Tool) GetSpoolId() string {
+	if x != nil {
+		return x.SpoolId
+	}
+	return ""
+}
+
+// PrinterState is everything about a printer that changes while it runs
+type PrinterState struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Printer *Printer               `protobuf:"bytes,1,opt,name=printer,proto3" json:"printer,omitempty"`
+	// job is unset if the printer has no current job
+	Job           *Job    `protobuf:"bytes,2,opt,name=job,proto3" json:"job,omitempty"`
+	Tools         []*Tool `protobuf:"bytes,3,rep,name=tools,proto3" json:"tools,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrinterState) Reset() {
+	*x = PrinterState{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrinterState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrinterState) ProtoMessage() {}
+
+func (x *PrinterState) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrinterState.ProtoReflect.Descriptor instead.
+func (*PrinterState) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PrinterState) GetPrinter() *Printer {
+	if x != nil {
+		return x.Printer
+	}
+	return nil
+}
+
+func (x *PrinterState) GetJob() *Job {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+func (x *PrinterState) GetTools() []*Tool {
+	if x != nil {
+		return x.Tools
+	}
+	return nil
+}
+
+// Operation is a long-running command, like a print or a filament change
+type Operation struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type    string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Printer string                 `protobuf:"bytes,3,opt,name=printer,proto3" json:"printer,omitempty"`
+	// status is "pending", "running", "succeeded" or "failed"
+	Status    string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	Step      string                 `protobuf:"bytes,5,opt,name=step,proto3" json:"step,omitempty"`
+	Error     string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// transfer is how far along sending the print file to the printer is
+	Transfer      *TransferProgress `protobuf:"bytes,9,opt,name=transfer,proto3" json:"transfer,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Operation) Reset() {
+	*x = Operation{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Operation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Operation) ProtoMessage() {}
+
+func (x *Operation) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Operation.ProtoReflect.Descriptor instead.
+func (*Operation) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Operation) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Operation) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Operation) GetPrinter() string {
+	if x != nil {
+		return x.Printer
+	}
+	return ""
+}
+
+func (x *Operation) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Operation) GetStep() string {
+	if x != nil {
+		return x.Step
+	}
+	return ""
+}
+
+func (x *Operation) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Operation) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Operation) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Operation) GetTransfer() *TransferProgress {
+	if x != nil {
+		return x.Transfer
+	}
+	return nil
+}
+
+type TransferProgress struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	BytesSent int64                  `protobuf:"varint,1,opt,name=bytes_sent,json=bytesSent,proto3" json:"bytes_sent,omitempty"`
+	Total     int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	// rate is in bytes per second
+	Rate float64 `protobuf:"fixed64,3,opt,name=rate,proto3" json:"rate,omitempty"`
+	// eta_seconds is how long until the transfer is done, if it can be estimated
+	EtaSeconds    *float64 `protobuf:"fixed64,4,opt,name=eta_seconds,json=etaSeconds,proto3,oneof" json:"eta_seconds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferProgress) Reset() {
+	*x = TransferProgress{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferProgress) ProtoMessage() {}
+
+func (x *TransferProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferProgress.ProtoReflect.Descriptor instead.
+func (*TransferProgress) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TransferProgress) GetBytesSent() int64 {
+	if x != nil {
+		return x.BytesSent
+	}
+	return 0
+}
+
+func (x *TransferProgress) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *TransferProgress) GetRate() float64 {
+	if x != nil {
+		return x.Rate
+	}
+	return 0
+}
+
+func (x *TransferProgress) GetEtaSeconds() float64 {
+	if x != nil && x.EtaSeconds != nil {
+		return *x.EtaSeconds
+	}
+	return 0
+}
+
+// CameraFrame is a camera frame encoded as an image
+type CameraFrame struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	ContentType   string                 `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Width         int32                  `protobuf:"varint,3,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32                  `protobuf:"varint,4,opt,name=height,proto3" json:"height,omitempty"`
+	FetchedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=fetched_at,json=fetchedAt,proto3" json:"fetched_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CameraFrame) Reset() {
+	*x = CameraFrame{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CameraFrame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CameraFrame) ProtoMessage() {}
+
+func (x *CameraFrame) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CameraFrame.ProtoReflect.Descriptor instead.
+func (*CameraFrame) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CameraFrame) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *CameraFrame) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *CameraFrame) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *CameraFrame) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *CameraFrame) GetFetchedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FetchedAt
+	}
+	return nil
+}
+
+type ListPrintersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// tag only lists printers configured with this tag, if set
+	Tag           string `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPrintersRequest) Reset() {
+	*x = ListPrintersRequest{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPrintersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPrintersRequest) ProtoMessage() {}
+
+func (x *ListPrintersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPrintersRequest.ProtoReflect.Descriptor instead.
+func (*ListPrintersRequest) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListPrintersRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+type ListPrintersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Printers      []*Printer             `protobuf:"bytes,1,rep,name=printers,proto3" json:"printers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPrintersResponse) Reset() {
+	*x = ListPrintersResponse{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPrintersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPrintersResponse) ProtoMessage() {}
+
+func (x *ListPrintersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPrintersResponse.ProtoReflect.Descriptor instead.
+func (*ListPrintersResponse) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListPrintersResponse) GetPrinters() []*Printer {
+	if x != nil {
+		return x.Printers
+	}
+	return nil
+}
+
+type GetPrinterRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PrinterId     string                 `protobuf:"bytes,1,opt,name=printer_id,json=printerId,proto3" json:"printer_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPrinterRequest) Reset() {
+	*x = GetPrinterRequest{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPrinterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPrinterRequest) ProtoMessage() {}
+
+func (x *GetPrinterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPrinterRequest.ProtoReflect.Descriptor instead.
+func (*GetPrinterRequest) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetPrinterRequest) GetPrinterId() string {
+	if x != nil {
+		return x.PrinterId
+	}
+	return ""
+}
+
+type GetJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PrinterId     string                 `protobuf:"bytes,1,opt,name=printer_id,json=printerId,proto3" json:"printer_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobRequest) Reset() {
+	*x = GetJobRequest{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobRequest) ProtoMessage() {}
+
+func (x *GetJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobRequest.ProtoReflect.Descriptor instead.
+func (*GetJobRequest) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetJobRequest) GetPrinterId() string {
+	if x != nil {
+		return x.PrinterId
+	}
+	return ""
+}
+
+type PrintRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Data:
+	//
+	//	*PrintRequest_Header
+	//	*PrintRequest_Chunk
+	Data          isPrintRequest_Data `protobuf_oneof:"data"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrintRequest) Reset() {
+	*x = PrintRequest{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrintRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrintRequest) ProtoMessage() {}
+
+func (x *PrintRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrintRequest.ProtoReflect.Descriptor instead.
+func (*PrintRequest) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PrintRequest) GetData() isPrintRequest_Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *PrintRequest) GetHeader() *PrintHeader {
+	if x != nil {
+		if x, ok := x.Data.(*PrintRequest_Header); ok {
+			return x.Header
+		}
+	}
+	return nil
+}
+
+func (x *PrintRequest) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Data.(*PrintRequest_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isPrintRequest_Data interface {
+	isPrintRequest_Data()
+}
+
+type PrintRequest_Header struct {
+	// header must be the first message
+	Header *PrintHeader `protobuf:"bytes,1,opt,name=header,proto3,oneof"`
+}
+
+type PrintRequest_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"`
+}
+
+func (*PrintRequest_Header) isPrintRequest_Data() {}
+
+func (*PrintRequest_Chunk) isPrintRequest_Data() {}
+
+type PrintHeader struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PrinterId     string                 `protobuf:"bytes,1,opt,name=printer_id,json=printerId,proto3" json:"printer_id,omitempty"`
+	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrintHeader) Reset() {
+	*x = PrintHeader{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrintHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrintHeader) ProtoMessage() {}
+
+func (x *PrintHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrintHeader.ProtoReflect.Descriptor instead.
+func (*PrintHeader) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PrintHeader) GetPrinterId() string {
+	if x != nil {
+		return x.PrinterId
+	}
+	return ""
+}
+
+func (x *PrintHeader) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+type PrintURLRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	PrinterId string                 `protobuf:"bytes,1,opt,name=printer_id,json=printerId,proto3" json:"printer_id,omitempty"`
+	Url       string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	// headers are sent along with the download request, e.g. for authentication
+	Headers       map[string]string `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrintURLRequest) Reset() {
+	*x = PrintURLRequest{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrintURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrintURLRequest) ProtoMessage() {}
+
+func (x *PrintURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrintURLRequest.ProtoReflect.Descriptor instead.
+func (*PrintURLRequest) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PrintURLRequest) GetPrinterId() string {
+	if x != nil {
+		return x.PrinterId
+	}
+	return ""
+}
+
+func (x *PrintURLRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *PrintURLRequest) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+type CancelJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PrinterId     string                 `protobuf:"bytes,1,opt,name=printer_id,json=printerId,proto3" json:"printer_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelJobRequest) Reset() {
+	*x = CancelJobRequest{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelJobRequest) ProtoMessage() {}
+
+func (x *CancelJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelJobRequest.ProtoReflect.Descriptor instead.
+func (*CancelJobRequest) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CancelJobRequest) GetPrinterId() string {
+	if x != nil {
+		return x.PrinterId
+	}
+	return ""
+}
+
+type CancelJobResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelJobResponse) Reset() {
+	*x = CancelJobResponse{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelJobResponse) ProtoMessage() {}
+
+func (x *CancelJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelJobResponse.ProtoReflect.Descriptor instead.
+func (*CancelJobResponse) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{16}
+}
+
+type RunJobActionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PrinterId     string                 `protobuf:"bytes,1,opt,name=printer_id,json=printerId,proto3" json:"printer_id,omitempty"`
+	Action        string                 `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunJobActionRequest) Reset() {
+	*x = RunJobActionRequest{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunJobActionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunJobActionRequest) ProtoMessage() {}
+
+func (x *RunJobActionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunJobActionRequest.ProtoReflect.Descriptor instead.
+func (*RunJobActionRequest) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RunJobActionRequest) GetPrinterId() string {
+	if x != nil {
+		return x.PrinterId
+	}
+	return ""
+}
+
+func (x *RunJobActionRequest) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+type RunJobActionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunJobActionResponse) Reset() {
+	*x = RunJobActionResponse{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunJobActionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunJobActionResponse) ProtoMessage() {}
+
+func (x *RunJobActionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunJobActionResponse.ProtoReflect.Descriptor instead.
+func (*RunJobActionResponse) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{18}
+}
+
+type LoadFilamentRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	PrinterId string                 `protobuf:"bytes,1,opt,name=printer_id,json=printerId,proto3" json:"printer_id,omitempty"`
+	Tool      int32                  `protobuf:"varint,2,opt,name=tool,proto3" json:"tool,omitempty"`
+	// spool_id is the spool being loaded, if it is in the inventory
+	SpoolId       string `protobuf:"bytes,3,opt,name=spool_id,json=spoolId,proto3" json:"spool_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoadFilamentRequest) Reset() {
+	*x = LoadFilamentRequest{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoadFilamentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoadFilamentRequest) ProtoMessage() {}
+
+func (x *LoadFilamentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoadFilamentRequest.ProtoReflect.Descriptor instead.
+func (*LoadFilamentRequest) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *LoadFilamentRequest) GetPrinterId() string {
+	if x != nil {
+		return x.PrinterId
+	}
+	return ""
+}
+
+func (x *LoadFilamentRequest) GetTool() int32 {
+	if x != nil {
+		return x.Tool
+	}
+	return 0
+}
+
+func (x *LoadFilamentRequest) GetSpoolId() string {
+	if x != nil {
+		return x.SpoolId
+	}
+	return ""
+}
+
+type UnloadFilamentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PrinterId     string                 `protobuf:"bytes,1,opt,name=printer_id,json=printerId,proto3" json:"printer_id,omitempty"`
+	Tool          int32                  `protobuf:"varint,2,opt,name=tool,proto3" json:"tool,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnloadFilamentRequest) Reset() {
+	*x = UnloadFilamentRequest{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnloadFilamentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnloadFilamentRequest) ProtoMessage() {}
+
+func (x *UnloadFilamentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnloadFilamentRequest.ProtoReflect.Descriptor instead.
+func (*UnloadFilamentRequest) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *UnloadFilamentRequest) GetPrinterId() string {
+	if x != nil {
+		return x.PrinterId
+	}
+	return ""
+}
+
+func (x *UnloadFilamentRequest) GetTool() int32 {
+	if x != nil {
+		return x.Tool
+	}
+	return 0
+}
+
+type GetOperationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OperationId   string                 `protobuf:"bytes,1,opt,name=operation_id,json=operationId,proto3" json:"operation_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOperationRequest) Reset() {
+	*x = GetOperationRequest{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOperationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOperationRequest) ProtoMessage() {}
+
+func (x *GetOperationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOperationRequest.ProtoReflect.Descriptor instead.
+func (*GetOperationRequest) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GetOperationRequest) GetOperationId() string {
+	if x != nil {
+		return x.OperationId
+	}
+	return ""
+}
+
+type WatchPrinterRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PrinterId     string                 `protobuf:"bytes,1,opt,name=printer_id,json=printerId,proto3" json:"printer_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchPrinterRequest) Reset() {
+	*x = WatchPrinterRequest{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchPrinterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPrinterRequest) ProtoMessage() {}
+
+func (x *WatchPrinterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPrinterRequest.ProtoReflect.Descriptor instead.
+func (*WatchPrinterRequest) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *WatchPrinterRequest) GetPrinterId() string {
+	if x != nil {
+		return x.PrinterId
+	}
+	return ""
+}
+
+type WatchCameraRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	PrinterId string                 `protobuf:"bytes,1,opt,name=printer_id,json=printerId,proto3" json:"printer_id,omitempty"`
+	// format is "jpeg" or "png". Defaults to "jpeg".
+	Format string `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`
+	// width scales frames to this many pixels wide. 0 keeps the original size.
+	Width int32 `protobuf:"varint,3,opt,name=width,proto3" json:"width,omitempty"`
+	// quality is the JPEG quality, 1-100
+	Quality int32 `protobuf:"varint,4,opt,name=quality,proto3" json:"quality,omitempty"`
+	// rotate is clockwise, in degrees. Must be a multiple of 90.
+	Rotate        int32 `protobuf:"varint,5,opt,name=rotate,proto3" json:"rotate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchCameraRequest) Reset() {
+	*x = WatchCameraRequest{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchCameraRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchCameraRequest) ProtoMessage() {}
+
+func (x *WatchCameraRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchCameraRequest.ProtoReflect.Descriptor instead.
+func (*WatchCameraRequest) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *WatchCameraRequest) GetPrinterId() string {
+	if x != nil {
+		return x.PrinterId
+	}
+	return ""
+}
+
+func (x *WatchCameraRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *WatchCameraRequest) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *WatchCameraRequest) GetQuality() int32 {
+	if x != nil {
+		return x.Quality
+	}
+	return 0
+}
+
+func (x *WatchCameraRequest) GetRotate() int32 {
+	if x != nil {
+		return x.Rotate
+	}
+	return 0
+}
+
+type WatchOperationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OperationId   string                 `protobuf:"bytes,1,opt,name=operation_id,json=operationId,proto3" json:"operation_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchOperationRequest) Reset() {
+	*x = WatchOperationRequest{}
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchOperationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchOperationRequest) ProtoMessage() {}
+
+func (x *WatchOperationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makerbotdpb_makerbotd_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchOperationRequest.ProtoReflect.Descriptor instead.
+func (*WatchOperationRequest) Descriptor() ([]byte, []int) {
+	return file_makerbotdpb_makerbotd_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *WatchOperationRequest) GetOperationId() string {
+	if x != nil {
+		return x.OperationId
+	}
+	return ""
+}
+
+var File_makerbotdpb_makerbotd_proto protoreflect.FileDescriptor
+
+const file_makerbotdpb_makerbotd_proto_rawDesc = "" +
+	"\n" +
+	"\x1bmakerbotdpb/makerbotd.proto\x12\fmakerbotd.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x8e\x02\n" +
+	"\aPrinter\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06serial\x18\x03 \x01(\tR\x06serial\x12!\n" +
+	"\fmachine_name\x18\x04 \x01(\tR\vmachineName\x12'\n" +
+	"\x0fconnection_type\x18\x05 \x01(\tR\x0econnectionType\x12\x12\n" +
+	"\x04tags\x18\x06 \x03(\tR\x04tags\x12\x1c\n" +
+	"\tconnected\x18\a \x01(\bR\tconnected\x12\x1a\n" +
+	"\brevision\x18\b \x01(\x04R\brevision\x12-\n" +
+	"\x04info\x18\t \x01(\v2\x19.makerbotd.v1.PrinterInfoR\x04info\"\xca\x01\n" +
+	"\vPrinterInfo\x12!\n" +
+	"\fmachine_type\x18\x01 \x01(\tR\vmachineType\x12!\n" +
+	"\fmachine_name\x18\x02 \x01(\tR\vmachineName\x12\x19\n" +
+	"\bbot_type\x18\x03 \x01(\tR\abotType\x12\x0e\n" +
+	"\x02ip\x18\x04 \x01(\tR\x02ip\x12)\n" +
+	"\x10firmware_version\x18\x05 \x01(\tR\x0ffirmwareVersion\x12\x1f\n" +
+	"\vapi_version\x18\x06 \x01(\tR\n" +
+	"apiVersion\"\x8f\x03\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04step\x18\x03 \x01(\tR\x04step\x12\x1a\n" +
+	"\bprogress\x18\x04 \x01(\x05R\bprogress\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\x12 \n" +
+	"\vcancellable\x18\x06 \x01(\bR\vcancellable\x12\x1c\n" +
+	"\tcancelled\x18\a \x01(\bR\tcancelled\x12\x1a\n" +
+	"\bcomplete\x18\b \x01(\bR\bcomplete\x12\x18\n" +
+	"\amethods\x18\t \x03(\tR\amethods\x12!\n" +
+	"\felapsed_time\x18\n" +
+	" \x01(\x05R\velapsedTime\x12\x1a\n" +
+	"\bfilename\x18\v \x01(\tR\bfilename\x129\n" +
+	"\n" +
+	"started_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12,\n" +
+	"\x03eta\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\x03eta\"\xe5\x01\n" +
+	"\x04Tool\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12/\n" +
+	"\x13current_temperature\x18\x02 \x01(\x05R\x12currentTemperature\x12-\n" +
+	"\x12target_temperature\x18\x03 \x01(\x05R\x11targetTemperature\x12)\n" +
+	"\x10filament_present\x18\x04 \x01(\bR\x0ffilamentPresent\x12!\n" +
+	"\ftool_present\x18\x05 \x01(\bR\vtoolPresent\x12\x19\n" +
+	"\bspool_id\x18\x06 \x01(\tR\aspoolId\"\x8e\x01\n" +
+	"\fPrinterState\x12/\n" +
+	"\aprinter\x18\x01 \x01(\v2\x15.makerbotd.v1.PrinterR\aprinter\x12#\n" +
+	"\x03job\x18\x02 \x01(\v2\x11.makerbotd.v1.JobR\x03job\x12(\n" +
+	"\x05tools\x18\x03 \x03(\v2\x12.makerbotd.v1.ToolR\x05tools\"\xbd\x02\n" +
+	"\tOperation\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x18\n" +
+	"\aprinter\x18\x03 \x01(\tR\aprinter\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x12\x12\n" +
+	"\x04step\x18\x05 \x01(\tR\x04step\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12:\n" +
+	"\btransfer\x18\t \x01(\v2\x1e.makerbotd.v1.TransferProgressR\btransfer\"\x91\x01\n" +
+	"\x10TransferProgress\x12\x1d\n" +
+	"\n" +
+	"bytes_sent\x18\x01 \x01(\x03R\tbytesSent\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\x12\x12\n" +
+	"\x04rate\x18\x03 \x01(\x01R\x04rate\x12$\n" +
+	"\veta_seconds\x18\x04 \x01(\x01H\x00R\n" +
+	"etaSeconds\x88\x01\x01B\x0e\n" +
+	"\f_eta_seconds\"\xad\x01\n" +
+	"\vCameraFrame\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12!\n" +
+	"\fcontent_type\x18\x02 \x01(\tR\vcontentType\x12\x14\n" +
+	"\x05width\x18\x03 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x04 \x01(\x05R\x06height\x129\n" +
+	"\n" +
+	"fetched_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tfetchedAt\"'\n" +
+	"\x13ListPrintersRequest\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\"I\n" +
+	"\x14ListPrintersResponse\x121\n" +
+	"\bprinters\x18\x01 \x03(\v2\x15.makerbotd.v1.PrinterR\bprinters\"2\n" +
+	"\x11GetPrinterRequest\x12\x1d\n" +
+	"\n" +
+	"printer_id\x18\x01 \x01(\tR\tprinterId\".\n" +
+	"\rGetJobRequest\x12\x1d\n" +
+	"\n" +
+	"printer_id\x18\x01 \x01(\tR\tprinterId\"c\n" +
+	"\fPrintRequest\x123\n" +
+	"\x06header\x18\x01 \x01(\v2\x19.makerbotd.v1.PrintHeaderH\x00R\x06header\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\x06\n" +
+	"\x04data\"H\n" +
+	"\vPrintHeader\x12\x1d\n" +
+	"\n" +
+	"printer_id\x18\x01 \x01(\tR\tprinterId\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\"\xc4\x01\n" +
+	"\x0fPrintURLRequest\x12\x1d\n" +
+	"\n" +
+	"printer_id\x18\x01 \x01(\tR\tprinterId\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12D\n" +
+	"\aheaders\x18\x03 \x03(\v2*.makerbotd.v1.PrintURLRequest.HeadersEntryR\aheaders\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"1\n" +
+	"\x10CancelJobRequest\x12\x1d\n" +
+	"\n" +
+	"printer_id\x18\x01 \x01(\tR\tprinterId\"\x13\n" +
+	"\x11CancelJobResponse\"L\n" +
+	"\x13RunJobActionRequest\x12\x1d\n" +
+	"\n" +
+	"printer_id\x18\x01 \x01(\tR\tprinterId\x12\x16\n" +
+	"\x06action\x18\x02 \x01(\tR\x06action\"\x16\n" +
+	"\x14RunJobActionResponse\"c\n" +
+	"\x13LoadFilamentRequest\x12\x1d\n" +
+	"\n" +
+	"printer_id\x18\x01 \x01(\tR\tprinterId\x12\x12\n" +
+	"\x04tool\x18\x02 \x01(\x05R\x04tool\x12\x19\n" +
+	"\bspool_id\x18\x03 \x01(\tR\aspoolId\"J\n" +
+	"\x15UnloadFilamentRequest\x12\x1d\n" +
+	"\n" +
+	"printer_id\x18\x01 \x01(\tR\tprinterId\x12\x12\n" +
+	"\x04tool\x18\x02 \x01(\x05R\x04tool\"8\n" +
+	"\x13GetOperationRequest\x12!\n" +
+	"\foperation_id\x18\x01 \x01(\tR\voperationId\"4\n" +
+	"\x13WatchPrinterRequest\x12\x1d\n" +
+	"\n" +
+	"printer_id\x18\x01 \x01(\tR\tprinterId\"\x93\x01\n" +
+	"\x12WatchCameraRequest\x12\x1d\n" +
+	"\n" +
+	"printer_id\x18\x01 \x01(\tR\tprinterId\x12\x16\n" +
+	"\x06format\x18\x02 \x01(\tR\x06format\x12\x14\n" +
+	"\x05width\x18\x03 \x01(\x05R\x05width\x12\x18\n" +
+	"\aquality\x18\x04 \x01(\x05R\aquality\x12\x16\n" +
+	"\x06rotate\x18\x05 \x01(\x05R\x06rotate\":\n" +
+	"\x15WatchOperationRequest\x12!\n" +
+	"\foperation_id\x18\x01 \x01(\tR\voperationId2\xe4\a\n" +
+	"\tMakerbotd\x12U\n" +
+	"\fListPrinters\x12!.makerbotd.v1.ListPrintersRequest\x1a\".makerbotd.v1.ListPrintersResponse\x12D\n" +
+	"\n" +
+	"GetPrinter\x12\x1f.makerbotd.v1.GetPrinterRequest\x1a\x15.makerbotd.v1.Printer\x128\n" +
+	"\x06GetJob\x12\x1b.makerbotd.v1.GetJobRequest\x1a\x11.makerbotd.v1.Job\x12>\n" +
+	"\x05Print\x12\x1a.makerbotd.v1.PrintRequest\x1a\x17.makerbotd.v1.Operation(\x01\x12B\n" +
+	"\bPrintURL\x12\x1d.makerbotd.v1.PrintURLRequest\x1a\x17.makerbotd.v1.Operation\x12L\n" +
+	"\tCancelJob\x12\x1e.makerbotd.v1.CancelJobRequest\x1a\x1f.makerbotd.v1.CancelJobResponse\x12U\n" +
+	"\fRunJobAction\x12!.makerbotd.v1.RunJobActionRequest\x1a\".makerbotd.v1.RunJobActionResponse\x12J\n" +
+	"\fLoadFilament\x12!.makerbotd.v1.LoadFilamentRequest\x1a\x17.makerbotd.v1.Operation\x12N\n" +
+	"\x0eUnloadFilament\x12#.makerbotd.v1.UnloadFilamentRequest\x1a\x17.makerbotd.v1.Operation\x12J\n" +
+	"\fGetOperation\x12!.makerbotd.v1.GetOperationRequest\x1a\x17.makerbotd.v1.Operation\x12O\n" +
+	"\fWatchPrinter\x12!.makerbotd.v1.WatchPrinterRequest\x1a\x1a.makerbotd.v1.PrinterState0\x01\x12L\n" +
+	"\vWatchCamera\x12 .makerbotd.v1.WatchCameraRequest\x1a\x19.makerbotd.v1.CameraFrame0\x01\x12P\n" +
+	"\x0eWatchOperation\x12#.makerbotd.v1.WatchOperationRequest\x1a\x17.makerbotd.v1.Operation0\x01B\x17Z\x15makerbotd/makerbotdpbb\x06proto3"
+
+var (
+	file_makerbotdpb_makerbotd_proto_rawDescOnce sync.Once
+	file_makerbotdpb_makerbotd_proto_rawDescData []byte
+)
+
+func file_makerbotdpb_makerbotd_proto_rawDescGZIP() []byte {
+	file_makerbotdpb_makerbotd_proto_rawDescOnce.Do(func() {
+		file_makerbotdpb_makerbotd_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_makerbotdpb_makerbotd_proto_rawDesc), len(file_makerbotdpb_makerbotd_proto_rawDesc)))
+	})
+	return file_makerbotdpb_makerbotd_proto_rawDescData
+}
+
+var file_makerbotdpb_makerbotd_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
+var file_makerbotdpb_makerbotd_proto_goTypes = []any{
+	(*Printer)(nil),               // 0: makerbotd.v1.Printer
+	(*PrinterInfo)(nil),           // 1: makerbotd.v1.PrinterInfo
+	(*Job)(nil),                   // 2: makerbotd.v1.Job
+	(*Tool)(nil),                  // 3: makerbotd.v1.Tool
+	(*PrinterState)(nil),          // 4: makerbotd.v1.PrinterState
+	(*Operation)(nil),             // 5: makerbotd.v1.Operation
+	(*TransferProgress)(nil),      // 6: makerbotd.v1.TransferProgress
+	(*CameraFrame)(nil),           // 7: makerbotd.v1.CameraFrame
+	(*ListPrintersRequest)(nil),   // 8: makerbotd.v1.ListPrintersRequest
+	(*ListPrintersResponse)(nil),  // 9: makerbotd.v1.ListPrintersResponse
+	(*GetPrinterRequest)(nil),     // 10: makerbotd.v1.GetPrinterRequest
+	(*GetJobRequest)(nil),         // 11: makerbotd.v1.GetJobRequest
+	(*PrintRequest)(nil),          // 12: makerbotd.v1.PrintRequest
+	(*PrintHeader)(nil),           // 13: makerbotd.v1.PrintHeader
+	(*PrintURLRequest)(nil),       // 14: makerbotd.v1.PrintURLRequest
+	(*CancelJobRequest)(nil),      // 15: makerbotd.v1.CancelJobRequest
+	(*CancelJobResponse)(nil),     // 16: makerbotd.v1.CancelJobResponse
+	(*RunJobActionRequest)(nil),   // 17: makerbotd.v1.RunJobActionRequest
+	(*RunJobActionResponse)(nil),  // 18: makerbotd.v1.RunJobActionResponse
+	(*LoadFilamentRequest)(nil),   // 19: makerbotd.v1.LoadFilamentRequest
+	(*UnloadFilamentRequest)(nil), // 20: makerbotd.v1.UnloadFilamentRequest
+	(*GetOperationRequest)(nil),   // 21: makerbotd.v1.GetOperationRequest
+	(*WatchPrinterRequest)(nil),   // 22: makerbotd.v1.WatchPrinterRequest
+	(*WatchCameraRequest)(nil),    // 23: makerbotd.v1.WatchCameraRequest
+	(*WatchOperationRequest)(nil), // 24: makerbotd.v1.WatchOperationRequest
+	nil,                           // 25: makerbotd.v1.PrintURLRequest.HeadersEntry
+	(*timestamppb.Timestamp)(nil), // 26: google.protobuf.Timestamp
+}
+var file_makerbotdpb_makerbotd_proto_depIdxs = []int32{
+	1,  // 0: makerbotd.v1.Printer.info:type_name -> makerbotd.v1.PrinterInfo
+	26, // 1: makerbotd.v1.Job.started_at:type_name -> google.protobuf.Timestamp
+	26, // 2: makerbotd.v1.Job.eta:type_name -> google.protobuf.Timestamp
+	0,  // 3: makerbotd.v1.PrinterState.printer:type_name -> makerbotd.v1.Printer
+	2,  // 4: makerbotd.v1.PrinterState.job:type_name -> makerbotd.v1.Job
+	3,  // 5: makerbotd.v1.PrinterState.tools:type_name -> makerbotd.v1.Tool
+	26, // 6: makerbotd.v1.Operation.created_at:type_name -> google.protobuf.Timestamp
+	26, // 7: makerbotd.v1.Operation.updated_at:type_name -> google.protobuf.Timestamp
+	6,  // 8: makerbotd.v1.Operation.transfer:type_name -> makerbotd.v1.TransferProgress
+	26, // 9: makerbotd.v1.CameraFrame.fetched_at:type_name -> google.protobuf.Timestamp
+	0,  // 10: makerbotd.v1.ListPrintersResponse.printers:type_name -> makerbotd.v1.Printer
+	13, // 11: makerbotd.v1.PrintRequest.header:type_name -> makerbotd.v1.PrintHeader
+	25, // 12: makerbotd.v1.PrintURLRequest.headers:type_name -> makerbotd.v1.PrintURLRequest.HeadersEntry
+	8,  // 13: makerbotd.v1.Makerbotd.ListPrinters:input_type -> makerbotd.v1.ListPrintersRequest
+	10, // 14: makerbotd.v1.Makerbotd.GetPrinter:input_type -> makerbotd.v1.GetPrinterRequest
+	11, // 15: makerbotd.v1.Makerbotd.GetJob:input_type -> makerbotd.v1.GetJobRequest
+	12, // 16: makerbotd.v1.Makerbotd.Print:input_type -> makerbotd.v1.PrintRequest
+	14, // 17: makerbotd.v1.Makerbotd.PrintURL:input_type -> makerbotd.v1.PrintURLRequest
+	15, // 18: makerbotd.v1.Makerbotd.CancelJob:input_type -> makerbotd.v1.CancelJobRequest
+	17, // 19: makerbotd.v1.Makerbotd.RunJobAction:input_type -> makerbotd.v1.RunJobActionRequest
+	19, // 20: makerbotd.v1.Makerbotd.LoadFilament:input_type -> makerbotd.v1.LoadFilamentRequest
+	20, // 21: makerbotd.v1.Makerbotd.UnloadFilament:input_type -> makerbotd.v1.UnloadFilamentRequest
+	21, // 22: makerbotd.v1.Makerbotd.GetOperation:input_type -> makerbotd.v1.GetOperationRequest
+	22, // 23: makerbotd.v1.Makerbotd.WatchPrinter:input_type -> makerbotd.v1.WatchPrinterRequest
+	23, // 24: makerbotd.v1.Makerbotd.WatchCamera:input_type -> makerbotd.v1.WatchCameraRequest
+	24, // 25: makerbotd.v1.Makerbotd.WatchOperation:input_type -> makerbotd.v1.WatchOperationRequest
+	9,  // 26: makerbotd.v1.Makerbotd.ListPrinters:output_type -> makerbotd.v1.ListPrintersResponse
+	0,  // 27: makerbotd.v1.Makerbotd.GetPrinter:output_type -> makerbotd.v1.Printer
+	2,  // 28: makerbotd.v1.Makerbotd.GetJob:output_type -> makerbotd.v1.Job
+	5,  // 29: makerbotd.v1.Makerbotd.Print:output_type -> makerbotd.v1.Operation
+	5,  // 30: makerbotd.v1.Makerbotd.PrintURL:output_type -> makerbotd.v1.Operation
+	16, // 31: makerbotd.v1.Makerbotd.CancelJob:output_type -> makerbotd.v1.CancelJobResponse
+	18, // 32: makerbotd.v1.Makerbotd.RunJobAction:output_type -> makerbotd.v1.RunJobActionResponse
+	5,  // 33: makerbotd.v1.Makerbotd.LoadFilament:output_type -> makerbotd.v1.Operation
+	5,  // 34: makerbotd.v1.Makerbotd.UnloadFilament:output_type -> makerbotd.v1.Operation
+	5,  // 35: makerbotd.v1.Makerbotd.GetOperation:output_type -> makerbotd.v1.Operation
+	4,  // 36: makerbotd.v1.Makerbotd.WatchPrinter:output_type -> makerbotd.v1.PrinterState
+	7,  // 37: makerbotd.v1.Makerbotd.WatchCamera:output_type -> makerbotd.v1.CameraFrame
+	5,  // 38: makerbotd.v1.Makerbotd.WatchOperation:output_type -> makerbotd.v1.Operation
+	26, // [26:39] is the sub-list for method output_type
+	13, // [13:26] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_makerbotdpb_makerbotd_proto_init() }
+func file_makerbotdpb_makerbotd_proto_init() {
+	if File_makerbotdpb_makerbotd_proto != nil {
+		return
+	}
+	file_makerbotdpb_makerbotd_proto_msgTypes[6].OneofWrappers = []any{}
+	file_makerbotdpb_makerbotd_proto_msgTypes[12].OneofWrappers = []any{
+		(*PrintRequest_Header)(nil),
+		(*PrintRequest_Chunk)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_makerbotdpb_makerbotd_proto_rawDesc), len(file_makerbotdpb_makerbotd_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   26,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_makerbotdpb_makerbotd_proto_goTypes,
+		DependencyIndexes: file_makerbotdpb_makerbotd_proto_depIdxs,
+		MessageInfos:      file_makerbotdpb_makerbotd_proto_msgTypes,
+	}.Build()
+	File_makerbotdpb_makerbotd_proto = out.File
+	file_makerbotdpb_makerbotd_proto_goTypes = nil
+	file_makerbotdpb_makerbotd_proto_depIdxs = nil
+}
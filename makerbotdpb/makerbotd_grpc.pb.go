@@ -0,0 +1,628 @@
+// The makerbotd gRPC service. Regenerate the Go stubs after changing this file:
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//     makerbotdpb/makerbotd.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: makerbotdpb/makerbotd.proto
+
+package makerbotdpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Makerbotd_ListPrinters_FullMethodName   = "/makerbotd.v1.Makerbotd/ListPrinters"
+	Makerbotd_GetPrinter_FullMethodName     = "/makerbotd.v1.Makerbotd/GetPrinter"
+	Makerbotd_GetJob_FullMethodName         = "/makerbotd.v1.Makerbotd/GetJob"
+	Makerbotd_Print_FullMethodName          = "/makerbotd.v1.Makerbotd/Print"
+	Makerbotd_PrintURL_FullMethodName       = "/makerbotd.v1.Makerbotd/PrintURL"
+	Makerbotd_CancelJob_FullMethodName      = "/makerbotd.v1.Makerbotd/CancelJob"
+	Makerbotd_RunJobAction_FullMethodName   = "/makerbotd.v1.Makerbotd/RunJobAction"
+	Makerbotd_LoadFilament_FullMethodName   = "/makerbotd.v1.Makerbotd/LoadFilament"
+	Makerbotd_UnloadFilament_FullMethodName = "/makerbotd.v1.Makerbotd/UnloadFilament"
+	Makerbotd_GetOperation_FullMethodName   = "/makerbotd.v1.Makerbotd/GetOperation"
+	Makerbotd_WatchPrinter_FullMethodName   = "/makerbotd.v1.Makerbotd/WatchPrinter"
+	Makerbotd_WatchCamera_FullMethodName    = "/makerbotd.v1.Makerbotd/WatchCamera"
+	Makerbotd_WatchOperation_FullMethodName = "/makerbotd.v1.Makerbotd/WatchOperation"
+)
+
+// MakerbotdClient is the client API for Makerbotd service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Makerbotd controls the printers makerbotd is connected to. Commands are unary
+// calls. Printer state and camera frames are streamed.
+type MakerbotdClient interface {
+	// ListPrinters lists every configured printer, whether or not it is connected
+	ListPrinters(ctx context.Context, in *ListPrintersRequest, opts ...grpc.CallOption) (*ListPrintersResponse, error)
+	// GetPrinter gets a printer by its name, serial or machine name
+	GetPrinter(ctx context.Context, in *GetPrinterRequest, opts ...grpc.CallOption) (*Printer, error)
+	// GetJob gets the printer's current job. It fails with NOT_FOUND if there is none.
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// Print sends a print file to the printer. The file is sent in chunks after a
+	// header, and the operation following the print is returned once all of it
+	// has been received. If the loaded spools are short on filament, a "warning"
+	// header says so.
+	Print(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PrintRequest, Operation], error)
+	// PrintURL downloads a print file and sends it to the printer
+	PrintURL(ctx context.Context, in *PrintURLRequest, opts ...grpc.CallOption) (*Operation, error)
+	// CancelJob cancels the printer's current job
+	CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*CancelJobResponse, error)
+	// RunJobAction runs one of the actions the current job accepts right now,
+	// such as "suspend" or "resume"
+	RunJobAction(ctx context.Context, in *RunJobActionRequest, opts ...grpc.CallOption) (*RunJobActionResponse, error)
+	// LoadFilament loads filament into a tool
+	LoadFilament(ctx context.Context, in *LoadFilamentRequest, opts ...grpc.CallOption) (*Operation, error)
+	// UnloadFilament unloads filament from a tool
+	UnloadFilament(ctx context.Context, in *UnloadFilamentRequest, opts ...grpc.CallOption) (*Operation, error)
+	// GetOperation gets an operation by its ID
+	GetOperation(ctx context.Context, in *GetOperationRequest, opts ...grpc.CallOption) (*Operation, error)
+	// WatchPrinter sends the printer's state right away and again every time it
+	// changes, until the client goes away
+	WatchPrinter(ctx context.Context, in *WatchPrinterRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PrinterState], error)
+	// WatchCamera sends camera frames from the printer as they change
+	WatchCamera(ctx context.Context, in *WatchCameraRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CameraFrame], error)
+	// WatchOperation sends the operation's state every time it changes, until it is done
+	WatchOperation(ctx context.Context, in *WatchOperationRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Operation], error)
+}
+
+type makerbotdClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMakerbotdClient(cc grpc.ClientConnInterface) MakerbotdClient {
+	return &makerbotdClient{cc}
+}
+
+func (c *makerbotdClient) ListPrinters(ctx context.Context, in *ListPrintersRequest, opts ...grpc.CallOption) (*ListPrintersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPrintersResponse)
+	err := c.cc.Invoke(ctx, Makerbotd_ListPrinters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makerbotdClient) GetPrinter(ctx context.Context, in *GetPrinterRequest, opts ...grpc.CallOption) (*Printer, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Printer)
+	err := c.cc.Invoke(ctx, Makerbotd_GetPrinter_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makerbotdClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Makerbotd_GetJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makerbotdClient) Print(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PrintRequest, Operation], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Makerbotd_ServiceDesc.Streams[0], Makerbotd_Print_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PrintRequest, Operation]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Makerbotd_PrintClient = grpc.ClientStreamingClient[PrintRequest, Operation]
+
+func (c *makerbotdClient) PrintURL(ctx context.Context, in *PrintURLRequest, opts ...grpc.CallOption) (*Operation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, Makerbotd_PrintURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makerbotdClient) CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*CancelJobResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelJobResponse)
+	err := c.cc.Invoke(ctx, Makerbotd_CancelJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makerbotdClient) RunJobAction(ctx context.Context, in *RunJobActionRequest, opts ...grpc.CallOption) (*RunJobActionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RunJobActionResponse)
+	err := c.cc.Invoke(ctx, Makerbotd_RunJobAction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makerbotdClient) LoadFilament(ctx context.Context, in *LoadFilamentRequest, opts ...grpc.CallOption) (*Operation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, Makerbotd_LoadFilament_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makerbotdClient) UnloadFilament(ctx context.Context, in *UnloadFilamentRequest, opts ...grpc.CallOption) (*Operation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, Makerbotd_UnloadFilament_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makerbotdClient) GetOperation(ctx context.Context, in *GetOperationRequest, opts ...grpc.CallOption) (*Operation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, Makerbotd_GetOperation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *makerbotdClient) WatchPrinter(ctx context.Context, in *WatchPrinterRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PrinterState], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Makerbotd_ServiceDesc.Streams[1], Makerbotd_WatchPrinter_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchPrinterRequest, PrinterState]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Makerbotd_WatchPrinterClient = grpc.ServerStreamingClient[PrinterState]
+
+func (c *makerbotdClient) WatchCamera(ctx context.Context, in *WatchCameraRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CameraFrame], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Makerbotd_ServiceDesc.Streams[2], Makerbotd_WatchCamera_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchCameraRequest, CameraFrame]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Makerbotd_WatchCameraClient = grpc.ServerStreamingClient[CameraFrame]
+
+func (c *makerbotdClient) WatchOperation(ctx context.Context, in *WatchOperationRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Operation], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Makerbotd_ServiceDesc.Streams[3], Makerbotd_WatchOperation_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchOperationRequest, Operation]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Makerbotd_WatchOperationClient = grpc.ServerStreamingClient[Operation]
+
+// MakerbotdServer is the server API for Makerbotd service.
+// All implementations must embed UnimplementedMakerbotdServer
+// for forward compatibility.
+//
+// Makerbotd controls the printers makerbotd is connected to. Commands are unary
+// calls. Printer state and camera frames are streamed.
+type MakerbotdServer interface {
+	// ListPrinters lists every configured printer, whether or not it is connected
+	ListPrinters(context.Context, *ListPrintersRequest) (*ListPrintersResponse, error)
+	// GetPrinter gets a printer by its name, serial or machine name
+	GetPrinter(context.Context, *GetPrinterRequest) (*Printer, error)
+	// GetJob gets the printer's current job. It fails with NOT_FOUND if there is none.
+	GetJob(context.Context, *GetJobRequest) (*Job, error)
+	// Print sends a print file to the printer. The file is sent in chunks after a
+	// header, and the operation following the print is returned once all of it
+	// has been received. If the loaded spools are short on filament, a "warning"
+	// header says so.
+	Print(grpc.ClientStreamingServer[PrintRequest, Operation]) error
+	// PrintURL downloads a print file and sends it to the printer
+	PrintURL(context.Context, *PrintURLRequest) (*Operation, error)
+	// CancelJob cancels the printer's current job
+	CancelJob(context.Context, *CancelJobRequest) (*CancelJobResponse, error)
+	// RunJobAction runs one of the actions the current job accepts right now,
+	// such as "suspend" or "resume"
+	RunJobAction(context.Context, *RunJobActionRequest) (*RunJobActionResponse, error)
+	// LoadFilament loads filament into a tool
+	LoadFilament(context.Context, *LoadFilamentRequest) (*Operation, error)
+	// UnloadFilament unloads filament from a tool
+	UnloadFilament(context.Context, *UnloadFilamentRequest) (*Operation, error)
+	// GetOperation gets an operation by its ID
+	GetOperation(context.Context, *GetOperationRequest) (*Operation, error)
+	// WatchPrinter sends the printer's state right away and again every time it
+	// changes, until the client goes away
+	WatchPrinter(*WatchPrinterRequest, grpc.ServerStreamingServer[PrinterState]) error
+	// WatchCamera sends camera frames from the printer as they change
+	WatchCamera(*WatchCameraRequest, grpc.ServerStreamingServer[CameraFrame]) error
+	// WatchOperation sends the operation's state every time it changes, until it is done
+	WatchOperation(*WatchOperationRequest, grpc.ServerStreamingServer[Operation]) error
+	mustEmbedUnimplementedMakerbotdServer()
+}
+
+// UnimplementedMakerbotdServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMakerbotdServer struct{}
+
+func (UnimplementedMakerbotdServer) ListPrinters(context.Context, *ListPrintersRequest) (*ListPrintersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPrinters not implemented")
+}
+func (UnimplementedMakerbotdServer) GetPrinter(context.Context, *GetPrinterRequest) (*Printer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPrinter not implemented")
+}
+func (UnimplementedMakerbotdServer) GetJob(context.Context, *GetJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedMakerbotdServer) Print(grpc.ClientStreamingServer[PrintRequest, Operation]) error {
+	return status.Errorf(codes.Unimplemented, "method Print not implemented")
+}
+func (UnimplementedMakerbotdServer) PrintURL(context.Context, *PrintURLRequest) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PrintURL not implemented")
+}
+func (UnimplementedMakerbotdServer) CancelJob(context.Context, *CancelJobRequest) (*CancelJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelJob not implemented")
+}
+func (UnimplementedMakerbotdServer) RunJobAction(context.Context, *RunJobActionRequest) (*RunJobActionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunJobAction not implemented")
+}
+func (UnimplementedMakerbotdServer) LoadFilament(context.Context, *LoadFilamentRequest) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LoadFilament not implemented")
+}
+func (UnimplementedMakerbotdServer) UnloadFilament(context.Context, *UnloadFilamentRequest) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnloadFilament not implemented")
+}
+func (UnimplementedMakerbotdServer) GetOperation(context.Context, *GetOperationRequest) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOperation not implemented")
+}
+func (UnimplementedMakerbotdServer) WatchPrinter(*WatchPrinterRequest, grpc.ServerStreamingServer[PrinterState]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPrinter not implemented")
+}
+func (UnimplementedMakerbotdServer) WatchCamera(*WatchCameraRequest, grpc.ServerStreamingServer[CameraFrame]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchCamera not implemented")
+}
+func (UnimplementedMakerbotdServer) WatchOperation(*WatchOperationRequest, grpc.ServerStreamingServer[Operation]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchOperation not implemented")
+}
+func (UnimplementedMakerbotdServer) mustEmbedUnimplementedMakerbotdServer() {}
+func (UnimplementedMakerbotdServer) testEmbeddedByValue()                   {}
+
+// UnsafeMakerbotdServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MakerbotdServer will
+// result in compilation errors.
+type UnsafeMakerbotdServer interface {
+	mustEmbedUnimplementedMakerbotdServer()
+}
+
+func RegisterMakerbotdServer(s grpc.ServiceRegistrar, srv MakerbotdServer) {
+	// If the following call pancis, it indicates UnimplementedMakerbotdServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Makerbotd_ServiceDesc, srv)
+}
+
+func _Makerbotd_ListPrinters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPrintersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakerbotdServer).ListPrinters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makerbotd_ListPrinters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakerbotdServer).ListPrinters(ctx, req.(*ListPrintersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makerbotd_GetPrinter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPrinterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakerbotdServer).GetPrinter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makerbotd_GetPrinter_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakerbotdServer).GetPrinter(ctx, req.(*GetPrinterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makerbotd_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakerbotdServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makerbotd_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakerbotdServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makerbotd_Print_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MakerbotdServer).Print(&grpc.GenericServerStream[PrintRequest, Operation]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Makerbotd_PrintServer = grpc.ClientStreamingServer[PrintRequest, Operation]
+
+func _Makerbotd_PrintURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PrintURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakerbotdServer).PrintURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makerbotd_PrintURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakerbotdServer).PrintURL(ctx, req.(*PrintURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makerbotd_CancelJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakerbotdServer).CancelJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makerbotd_CancelJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakerbotdServer).CancelJob(ctx, req.(*CancelJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makerbotd_RunJobAction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunJobActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakerbotdServer).RunJobAction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makerbotd_RunJobAction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakerbotdServer).RunJobAction(ctx, req.(*RunJobActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makerbotd_LoadFilament_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoadFilamentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakerbotdServer).LoadFilament(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makerbotd_LoadFilament_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakerbotdServer).LoadFilament(ctx, req.(*LoadFilamentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makerbotd_UnloadFilament_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnloadFilamentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakerbotdServer).UnloadFilament(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makerbotd_UnloadFilament_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakerbotdServer).UnloadFilament(ctx, req.(*UnloadFilamentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makerbotd_GetOperation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MakerbotdServer).GetOperation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Makerbotd_GetOperation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MakerbotdServer).GetOperation(ctx, req.(*GetOperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Makerbotd_WatchPrinter_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPrinterRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MakerbotdServer).WatchPrinter(m, &grpc.GenericServerStream[WatchPrinterRequest, PrinterState]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Makerbotd_WatchPrinterServer = grpc.ServerStreamingServer[PrinterState]
+
+func _Makerbotd_WatchCamera_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchCameraRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MakerbotdServer).WatchCamera(m, &grpc.GenericServerStream[WatchCameraRequest, CameraFrame]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Makerbotd_WatchCameraServer = grpc.ServerStreamingServer[CameraFrame]
+
+func _Makerbotd_WatchOperation_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchOperationRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MakerbotdServer).WatchOperation(m, &grpc.GenericServerStream[WatchOperationRequest, Operation]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Makerbotd_WatchOperationServer = grpc.ServerStreamingServer[Operation]
+
+// Makerbotd_ServiceDesc is the grpc.ServiceDesc for Makerbotd service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Makerbotd_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "makerbotd.v1.Makerbotd",
+	HandlerType: (*MakerbotdServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListPrinters",
+			Handler:    _Makerbotd_ListPrinters_Handler,
+		},
+		{
+			MethodName: "GetPrinter",
+			Handler:    _Makerbotd_GetPrinter_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _Makerbotd_GetJob_Handler,
+		},
+		{
+			MethodName: "PrintURL",
+			Handler:    _Makerbotd_PrintURL_Handler,
+		},
+		{
+			MethodName: "CancelJob",
+			Handler:    _Makerbotd_CancelJob_Handler,
+		},
+		{
+			MethodName: "RunJobAction",
+			Handler:    _Makerbotd_RunJobAction_Handler,
+		},
+		{
+			MethodName: "LoadFilament",
+			Handler:    _Makerbotd_LoadFilament_Handler,
+		},
+		{
+			MethodName: "UnloadFilament",
+			Handler:    _Makerbotd_UnloadFilament_Handler,
+		},
+		{
+			MethodName: "GetOperation",
+			Handler:    _Makerbotd_GetOperation_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Print",
+			Handler:       _Makerbotd_Print_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchPrinter",
+			Handler:       _Makerbotd_WatchPrinter_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchCamera",
+			Handler:       _Makerbotd_WatchCamera_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchOperation",
+			Handler:       _Makerbotd_WatchOperation_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "makerbotdpb/makerbotd.proto",
+}
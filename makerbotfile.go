@@ -0,0 +1,61 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// makerbotFileMeta is the part of a .makerbot file's meta.json that makerbotd
+// cares about
+type makerbotFileMeta struct {
+	DurationSeconds      float64   `json:"duration_s"`
+	ExtrusionDistancesMM []float64 `json:"extrusion_distances_mm"`
+	ExtrusionDistanceAMM float64   `json:"extrusion_distance_a_mm"`
+	ExtrusionDistanceBMM float64   `json:"extrusion_distance_b_mm"`
+}
+
+// FilamentMM returns how much filament, in millimeters, the print needs from each tool
+func (m *makerbotFileMeta) FilamentMM() []float64 {
+	if len(m.ExtrusionDistancesMM) > 0 {
+		return m.ExtrusionDistancesMM
+	}
+
+	// Older slicers only fill in the per-extruder fields
+	if m.ExtrusionDistanceBMM > 0 {
+		return []float64{m.ExtrusionDistanceAMM, m.ExtrusionDistanceBMM}
+	}
+
+	return []float64{m.ExtrusionDistanceAMM}
+}
+
+// readMakerbotFileMeta reads meta.json out of a .makerbot file, which is a zip archive
+func readMakerbotFileMeta(r io.ReaderAt, size int64) (*makerbotFileMeta, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range zr.File {
+		if f.Name != "meta.json" {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		var meta makerbotFileMeta
+		err = json.NewDecoder(rc).Decode(&meta)
+		if err != nil {
+			return nil, err
+		}
+
+		return &meta, nil
+	}
+
+	return nil, errors.New("meta.json not found in print file")
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// latencyBuckets are the upper bounds of the latency histograms, in seconds
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64 // counts has one count per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(latencyBuckets))
+	}
+
+	s := d.Seconds()
+	for i, le := range latencyBuckets {
+		if s <= le {
+			h.counts[i]++
+			break
+		}
+	}
+
+	h.sum += s
+	h.count++
+}
+
+// metrics holds everything makerbotd counts. Printer state is read when the
+// metrics are scraped, so only things that happen over time live here.
+type metrics struct {
+	sync.Mutex
+	reconnects     map[string]uint64
+	cameraFetches  map[string]*histogram
+	requests       map[[3]string]uint64     // requests are counted by method, route and status code
+	requestLatency map[[2]string]*histogram // request latency is tracked by method and route
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		reconnects:     map[string]uint64{},
+		cameraFetches:  map[string]*histogram{},
+		requests:       map[[3]string]uint64{},
+		requestLatency: map[[2]string]*histogram{},
+	}
+}
+
+func (m *metrics) CountReconnect(printer string) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.reconnects[printer]++
+}
+
+func (m *metrics) ObserveCameraFetch(printer string, d time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+
+	h, ok := m.cameraFetches[printer]
+	if !ok {
+		h = &histogram{}
+		m.cameraFetches[printer] = h
+	}
+
+	h.observe(d)
+}
+
+func (m *metrics) ObserveRequest(method, route string, code int, d time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.requests[[3]string{method, route, strconv.Itoa(code)}]++
+
+	key := [2]string{method, route}
+	h, ok := m.requestLatency[key]
+	if !ok {
+		h = &histogram{}
+		m.requestLatency[key] = h
+	}
+
+	h.observe(d)
+}
+
+// statusRecorder remembers the status code of a response, and the route the
+// request matched
+type statusRecorder struct {
+	http.ResponseWriter
+	code  int
+	route string
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	sr.code = code
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+// Flush keeps streaming responses working through the recorder
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// instrumentedRouter registers routes on an httprouter.Router so that every
+// request is counted under the route pattern it matched, e.g.
+// /api/v1/printers/:id, rather than with a label for every printer
+type instrumentedRouter struct {
+	*httprouter.Router
+}
+
+func newInstrumentedRouter() *instrumentedRouter {
+	return &instrumentedRouter{httprouter.New()}
+}
+
+func (ir *instrumentedRouter) Handle(method, path string, handle httprouter.Handle) {
+	ir.Router.Handle(method, path, func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		if sr, ok := w.(*statusRecorder); ok {
+			sr.route = path
+		}
+
+		handle(w, r, params)
+	})
+}
+
+func (ir *instrumentedRouter) Handler(method, path string, handler http.Handler) {
+	ir.Handle(method, path, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (ir *instrumentedRouter) HandlerFunc(method, path string, handler http.HandlerFunc) {
+	ir.Handler(method, path, handler)
+}
+
+func (ir *instrumentedRouter) GET(path string, handle httprouter.Handle) {
+	ir.Handle("GET", path, handle)
+}
+
+func (ir *instrumentedRouter) POST(path string, handle httprouter.Handle) {
+	ir.Handle("POST", path, handle)
+}
+
+func (ir *instrumentedRouter) PUT(path string, handle httprouter.Handle) {
+	ir.Handle("PUT", path, handle)
+}
+
+func (ir *instrumentedRouter) DELETE(path string, handle httprouter.Handle) {
+	ir.Handle("DELETE", path, handle)
+}
+
+// instrument counts and times every request that goes through `router`.
+// Requests that don't match a route are counted as "unmatched".
+func (m *metrics) instrument(router *instrumentedRouter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: w, code: http.StatusOK, route: "unmatched"}
+
+		router.ServeHTTP(sr, r)
+
+		m.ObserveRequest(r.Method, sr.route, sr.code, time.Since(start))
+	})
+}
+
+// labels formats label pairs for the exposition format
+func labels(pairs ...string) string {
+	parts := []string{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		v := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(pairs[i+1])
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, pairs[i], v))
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeHistogram(w io.Writer, name string, h *histogram, pairs ...string) {
+	var cumulative uint64
+	for i, le := range latencyBuckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels(append(pairs, "le", strconv.FormatFloat(le, 'g', -1, 64))...), cumulative)
+	}
+
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels(append(pairs, "le", "+Inf")...), h.count)
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, labels(pairs...), h.sum)
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels(pairs...), h.count)
+}
+
+// writePrinterMetrics writes the gauges that come from the printers' current state
+func (ctx *mbContext) writePrinterMetrics(w io.Writer) {
+	ctx.Printers.RLock()
+	printers := append([]*printerConnection{}, ctx.Printers.list...)
+	ctx.Printers.RUnlock()
+
+	writeHeader(w, "makerbotd_printer_up", "gauge", "Whether makerbotd is connected to the printer.")
+	for _, pc := range printers {
+		up := 0
+		if pc.backend() != nil {
+			up = 1
+		}
+
+		fmt.Fprintf(w, "makerbotd_printer_up%s %d\n", labels("printer", pc.ID()), up)
+	}
+
+	type state struct {
+		printer string
+		proc    *jobProgress
+		tools   []toolTemperature
+	}
+
+	states := []state{}
+	for _, pc := range printers {
+		if pc.backend() == nil {
+			continue
+		}
+
+		states = append(states, state{pc.ID(), pc.JobProgress(), pc.toolTemperatures()})
+	}
+
+	writeHeader(w, "makerbotd_printer_job_progress", "gauge", "Progress of the printer's current job, in percent.")
+	for _, s := range states {
+		if s.proc != nil {
+			fmt.Fprintf(w, "makerbotd_printer_job_progress%s %d\n", labels("printer", s.printer), s.proc.Progress)
+		}
+	}
+
+	writeHeader(w, "makerbotd_printer_job_state", "gauge", "The step the printer's current job is on. Always 1.")
+	for _, s := range states {
+		if s.proc != nil {
+			fmt.Fprintf(w, "makerbotd_printer_job_state%s 1\n", labels("printer", s.printer, "process", s.proc.Name, "step", s.proc.Step))
+		}
+	}
+
+	writeHeader(w, "makerbotd_printer_job_eta_seconds", "gauge", "Estimated seconds until the printer's current job is done.")
+	for _, s := range states {
+		if s.proc != nil && s.proc.ETA != nil {
+			fmt.Fprintf(w, "makerbotd_printer_job_eta_seconds%s %g\n", labels("printer", s.printer), time.Until(*s.proc.ETA).Seconds())
+		}
+	}
+
+	writeHeader(w, "makerbotd_printer_extruder_temperature_celsius", "gauge", "Current temperature of the extruder.")
+	for _, s := range states {
+		for _, t := range s.tools {
+			fmt.Fprintf(w, "makerbotd_printer_extruder_temperature_celsius%s %d\n", labels("printer", s.printer, "extruder", strconv.Itoa(t.Index)), t.Current)
+		}
+	}
+
+	writeHeader(w, "makerbotd_printer_extruder_target_temperature_celsius", "gauge", "Target temperature of the extruder.")
+	for _, s := range states {
+		for _, t := range s.tools {
+			fmt.Fprintf(w, "makerbotd_printer_extruder_target_temperature_celsius%s %d\n", labels("printer", s.printer, "extruder", strconv.Itoa(t.Index)), t.Target)
+		}
+	}
+}
+
+type toolTemperature struct {
+	Index   int
+	Current int
+	Target  int
+}
+
+func (pc *printerConnection) toolTemperatures() []toolTemperature {
+	conn := pc.backend()
+	if conn == nil {
+		return nil
+	}
+
+	printer := conn.Printer()
+	if printer == nil || printer.Metadata == nil {
+		return nil
+	}
+
+	temps := []toolTemperature{}
+	for i, t := range printer.Metadata.Toolheads.Extruder {
+		temps = append(temps, toolTemperature{Index: i, Current: t.CurrentTemperature, Target: t.TargetTemperature})
+	}
+
+	return temps
+}
+
+func (m *metrics) write(w io.Writer) {
+	m.Lock()
+	defer m.Unlock()
+
+	writeHeader(w, "makerbotd_printer_reconnect_attempts_total", "counter", "Times makerbotd tried to reconnect to the printer after it disconnected.")
+	for _, printer := range sortedKeys(m.reconnects) {
+		fmt.Fprintf(w, "makerbotd_printer_reconnect_attempts_total%s %d\n", labels("printer", printer), m.reconnects[printer])
+	}
+
+	writeHeader(w, "makerbotd_printer_camera_fetch_duration_seconds", "histogram", "How long fetching a camera frame from the printer takes.")
+	printers := []string{}
+	for printer := range m.cameraFetches {
+		printers = append(printers, printer)
+	}
+	sort.Strings(printers)
+
+	for _, printer := range printers {
+		writeHistogram(w, "makerbotd_printer_camera_fetch_duration_seconds", m.cameraFetches[printer], "printer", printer)
+	}
+
+	writeHeader(w, "makerbotd_http_requests_total", "counter", "HTTP requests by method, route and status code.")
+	requests := [][3]string{}
+	for key := range m.requests {
+		requests = append(requests, key)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return strings.Join(requests[i][:], " ") < strings.Join(requests[j][:], " ")
+	})
+
+	for _, key := range requests {
+		fmt.Fprintf(w, "makerbotd_http_requests_total%s %d\n", labels("method", key[0], "route", key[1], "code", key[2]), m.requests[key])
+	}
+
+	writeHeader(w, "makerbotd_http_request_duration_seconds", "histogram", "HTTP request latency by method and route.")
+	routes := [][2]string{}
+	for key := range m.requestLatency {
+		routes = append(routes, key)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i][0]+" "+routes[i][1] < routes[j][0]+" "+routes[j][1]
+	})
+
+	for _, key := range routes {
+		writeHistogram(w, "makerbotd_http_request_duration_seconds", m.requestLatency[key], "method", key[0], "route", key[1])
+	}
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// writeRuntimeMetrics writes what the old /_/stats handler used to report
+func writeRuntimeMetrics(w io.Writer) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	writeHeader(w, "go_goroutines", "gauge", "Number of goroutines that currently exist.")
+	fmt.Fprintf(w, "go_goroutines %d\n", runtime.NumGoroutine())
+
+	writeHeader(w, "go_memstats_alloc_bytes", "gauge", "Number of bytes allocated and still in use.")
+	fmt.Fprintf(w, "go_memstats_alloc_bytes %d\n", ms.Alloc)
+
+	writeHeader(w, "go_memstats_alloc_bytes_total", "counter", "Total number of bytes allocated, even if freed.")
+	fmt.Fprintf(w, "go_memstats_alloc_bytes_total %d\n", ms.TotalAlloc)
+
+	writeHeader(w, "go_memstats_sys_bytes", "gauge", "Number of bytes obtained from the system.")
+	fmt.Fprintf(w, "go_memstats_sys_bytes %d\n", ms.Sys)
+}
+
+// metricsHandler serves everything in the Prometheus text exposition format
+func metricsHandler(ctx *mbContext) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		ctx.writePrinterMetrics(w)
+		ctx.Metrics.write(w)
+		writeRuntimeMetrics(w)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrapeMetrics(t *testing.T, ctx *mbContext) string {
+	rec := httptest.NewRecorder()
+	getRouter(ctx).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, err := ioutil.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(body)
+}
+
+func TestMetricsPrinterLabels(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+
+	// Neither printer has a name or has connected yet, so neither has a serial
+	ctx.Printers.Add(newPrinterConnection(ctx, printerConfig{ConnectionType: connectionTypeLocal, IP: "192.0.2.1"}))
+	ctx.Printers.Add(newPrinterConnection(ctx, printerConfig{ConnectionType: connectionTypeLocal, IP: "192.0.2.2"}))
+	addFakePrinter(t, ctx, printerConfig{Name: "fake", ID: "1"})
+
+	body := scrapeMetrics(t, ctx)
+
+	for _, want := range []string{
+		`makerbotd_printer_up{printer="192.0.2.1"} 0`,
+		`makerbotd_printer_up{printer="192.0.2.2"} 0`,
+		`makerbotd_printer_up{printer="1"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics don't include %s:\n%s", want, body)
+		}
+	}
+
+	if strings.Contains(body, `printer=""`) {
+		t.Errorf("metrics include a printer without a label:\n%s", body)
+	}
+}
+
+func TestMetricsRoutes(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	addFakePrinter(t, ctx, printerConfig{Name: "printers", ID: "1"})
+
+	router := getRouter(ctx)
+
+	// The printer's name is also a literal segment of the route
+	for _, path := range []string{"/api/v1/printers/printers", "/api/v2/printers/printers/tools", "/nowhere"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	body := scrapeMetrics(t, ctx)
+
+	for _, want := range []string{
+		`makerbotd_http_requests_total{method="GET",route="/api/v1/printers/:id",code="200"} 1`,
+		`makerbotd_http_requests_total{method="GET",route="/api/v2/printers/:id/tools",code="200"} 1`,
+		`makerbotd_http_requests_total{method="GET",route="unmatched",code="404"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics don't include %s:\n%s", want, body)
+		}
+	}
+}
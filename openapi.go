@@ -0,0 +1,673 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// openAPIRoute documents a single route for the OpenAPI document
+type openAPIRoute struct {
+	Summary     string
+	Description string
+	Query       []openAPIParam
+	Body        map[string]interface{} // Body maps request content types to their schemas
+	Result      interface{}            // Result is the schema of the apiResult's result
+	Produces    map[string]interface{} // Produces replaces the apiResult envelope for routes that don't answer with it
+	Status      int                    // Status is the success status code, 200 if not set
+}
+
+type openAPIParam struct {
+	Name        string
+	Description string
+	Type        string
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func arrayOf(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": schema}
+}
+
+func schemaOf(kind string) map[string]interface{} {
+	return map[string]interface{}{"type": kind}
+}
+
+var binarySchema = map[string]interface{}{"type": "string", "format": "binary"}
+
+// v1Routes documents every route APIv1 can register. APIv1.Route refuses to
+// register a route that isn't in here.
+var v1Routes = map[string]openAPIRoute{
+	"GET /api/v1/openapi.json": {
+		Summary:  "Get this OpenAPI document",
+		Produces: map[string]interface{}{"application/json": schemaOf("object")},
+	},
+	"GET /api/v1/printers": {
+		Summary: "List connected printers",
+		Query:   []openAPIParam{{"tag", "Only list printers with this tag", "string"}},
+		Result:  arrayOf(ref("Printer")),
+	},
+	"GET /api/v1/printers/:id": {
+		Summary:     "Get a printer",
+		Description: "The state revision is returned in the X-Revision header and in the ETag. With `wait` and `since`, the request blocks until the revision is different from `since`.",
+		Query: []openAPIParam{
+			{"wait", "How long to wait for a state change, e.g. 30s", "string"},
+			{"since", "The revision to wait for a newer one than", "integer"},
+		},
+		Result: ref("Printer"),
+	},
+	"GET /api/v1/printers/:id/snapshot.jpg": {
+		Summary:  "Get a JPEG snapshot from the printer's camera",
+		Query:    snapshotParams,
+		Produces: map[string]interface{}{"image/jpeg": binarySchema},
+	},
+	"GET /api/v1/printers/:id/snapshot.png": {
+		Summary:  "Get a PNG snapshot from the printer's camera",
+		Query:    snapshotParams,
+		Produces: map[string]interface{}{"image/png": binarySchema},
+	},
+	"GET /api/v1/printers/:id/current_job": {
+		Summary: "Get the printer's current job along with its progress history and ETA",
+		Result:  ref("JobProgress"),
+	},
+	"GET /api/v1/printers/:id/current_job/methods": {
+		Summary: "List the methods the current job accepts",
+		Result:  arrayOf(schemaOf("string")),
+	},
+	"GET /api/v1/printers/:id/spools": {
+		Summary: "List the spools loaded into the printer",
+		Result:  arrayOf(ref("Spool")),
+	},
+	"GET /api/v1/printers/:id/operations": {
+		Summary: "List the printer's running and recently finished operations",
+		Result:  arrayOf(ref("Operation")),
+	},
+	"GET /api/v1/discover": {
+		Summary: "Look for printers on the local network",
+		Query:   []openAPIParam{{"timeout", "How long to wait for answers, up to 30s", "string"}},
+		Result:  arrayOf(ref("DiscoveredPrinter")),
+	},
+	"GET /api/v1/spools": {
+		Summary: "List every spool in the inventory",
+		Result:  arrayOf(ref("Spool")),
+	},
+	"GET /api/v1/spools/:spool_id": {
+		Summary: "Get a spool",
+		Result:  ref("Spool"),
+	},
+	"GET /api/v1/operations": {
+		Summary: "List running and recently finished operations",
+		Result:  arrayOf(ref("Operation")),
+	},
+	"GET /api/v1/operations/:operation_id": {
+		Summary: "Get an operation",
+		Result:  ref("Operation"),
+	},
+	"GET /api/v1/operations/:operation_id/events": {
+		Summary:     "Follow an operation",
+		Description: "A server-sent event stream with an `operation` event every time the operation changes. It ends when the operation is done.",
+		Produces:    map[string]interface{}{"text/event-stream": schemaOf("string")},
+	},
+	"POST /api/v1/printers/:id/current_job/suspend": {
+		Summary: "Suspend the current job",
+		Result:  schemaOf("boolean"),
+	},
+	"POST /api/v1/printers/:id/current_job/resume": {
+		Summary: "Resume the current job",
+		Result:  schemaOf("boolean"),
+	},
+	"POST /api/v1/printers/:id/current_job/process_method/:method": {
+		Summary:     "Call one of the current job's methods",
+		Description: "Answers 404 if no job is running, and 409 if the job doesn't accept the method right now.",
+		Result:      schemaOf("boolean"),
+	},
+	"DELETE /api/v1/printers/:id/current_job": {
+		Summary: "Cancel the current job",
+		Result:  schemaOf("boolean"),
+	},
+	"POST /api/v1/printers/:id/prints": {
+		Summary:     "Print a file",
+		Description: "The file can be uploaded as multipart form data, sent as the request body, or downloaded from a URL in a JSON body.",
+		Query:       []openAPIParam{{"filename", "The file's name, when the request body is the file", "string"}},
+		Body: map[string]interface{}{
+			"multipart/form-data": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"size":      map[string]interface{}{"type": "integer", "description": "The file's size. If it comes before printfile, the file is streamed to the printer."},
+					"printfile": binarySchema,
+				},
+				"required": []string{"printfile"},
+			},
+			"application/json":         ref("PrintURLRequest"),
+			"application/octet-stream": binarySchema,
+		},
+		Result: ref("Operation"),
+		Status: http.StatusAccepted,
+	},
+	"POST /api/v1/printers/:id/unload_filament/:tool_index": {
+		Summary: "Unload filament from a tool",
+		Result:  ref("Operation"),
+		Status:  http.StatusAccepted,
+	},
+	"POST /api/v1/printers/:id/load_filament/:tool_index": {
+		Summary: "Load filament into a tool",
+		Query:   []openAPIParam{{"spool", "The ID of the spool being loaded", "string"}},
+		Result:  ref("Operation"),
+		Status:  http.StatusAccepted,
+	},
+	"POST /api/v1/spools": {
+		Summary: "Add a spool to the inventory",
+		Body:    map[string]interface{}{"application/json": ref("Spool")},
+		Result:  ref("Spool"),
+	},
+	"DELETE /api/v1/spools/:spool_id": {
+		Summary: "Remove a spool from the inventory",
+		Result:  schemaOf("boolean"),
+	},
+	"POST /api/v1/printers/:id/rpc": {
+		Summary:     "Call a JSON-RPC method on the printer",
+		Description: "Only available when AdminToken is set. Requires `Authorization: Bearer <AdminToken>`.",
+		Body: map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"method": schemaOf("string"),
+					"params": schemaOf("object"),
+				},
+				"required": []string{"method"},
+			},
+		},
+		Result: map[string]interface{}{},
+	},
+}
+
+// v2Routes documents every route APIv2 can register
+var v2Routes = map[string]openAPIRoute{
+	"GET /api/v2/openapi.json": {
+		Summary:  "Get this OpenAPI document",
+		Produces: map[string]interface{}{"application/json": schemaOf("object")},
+	},
+	"GET /api/v2/printers": {
+		Summary: "List configured printers, connected or not",
+		Query:   append([]openAPIParam{{"tag", "Only list printers with this tag", "string"}}, listParams...),
+		Result:  listOf(ref("V2Printer")),
+	},
+	"GET /api/v2/printers/:id": {
+		Summary: "Get a printer",
+		Query:   fieldsParams,
+		Result:  ref("V2Printer"),
+	},
+	"GET /api/v2/printers/:id/job": {
+		Summary: "Get the printer's current job along with its progress history and ETA",
+		Query:   fieldsParams,
+		Result:  ref("JobProgress"),
+	},
+	"DELETE /api/v2/printers/:id/job": {
+		Summary: "Cancel the current job",
+		Status:  http.StatusNoContent,
+	},
+	"GET /api/v2/printers/:id/job/actions": {
+		Summary: "List the actions the current job accepts right now",
+		Query:   listParams,
+		Result:  listOf(schemaOf("string")),
+	},
+	"POST /api/v2/printers/:id/job/actions": {
+		Summary: "Run one of the current job's actions, such as suspend or resume",
+		Body: map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"action": schemaOf("string")},
+				"required":   []string{"action"},
+			},
+		},
+		Status: http.StatusNoContent,
+	},
+	"POST /api/v2/printers/:id/jobs": {
+		Summary:     "Start a print",
+		Description: "The body is the print file itself, a multipart upload with a printfile part, or a JSON object with the URL to download the file from.",
+		Query:       []openAPIParam{{"filename", "The file's name, when the body is the file", "string"}},
+		Body: map[string]interface{}{
+			"application/json": ref("PrintURLRequest"),
+			"multipart/form-data": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"printfile": binarySchema},
+				"required":   []string{"printfile"},
+			},
+			"application/octet-stream": binarySchema,
+		},
+		Result: ref("Operation"),
+		Status: http.StatusAccepted,
+	},
+	"GET /api/v2/printers/:id/tools": {
+		Summary: "List the printer's toolheads",
+		Query:   listParams,
+		Result:  listOf(ref("Tool")),
+	},
+	"GET /api/v2/printers/:id/tools/:tool": {
+		Summary: "Get a toolhead",
+		Query:   fieldsParams,
+		Result:  ref("Tool"),
+	},
+	"PUT /api/v2/printers/:id/tools/:tool/filament": {
+		Summary: "Load filament into a toolhead",
+		Body: map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"spool": map[string]interface{}{"type": "string", "description": "The ID of the spool being loaded"}},
+			},
+		},
+		Result: ref("Operation"),
+		Status: http.StatusAccepted,
+	},
+	"DELETE /api/v2/printers/:id/tools/:tool/filament": {
+		Summary: "Unload filament from a toolhead",
+		Result:  ref("Operation"),
+		Status:  http.StatusAccepted,
+	},
+	"GET /api/v2/printers/:id/camera": {
+		Summary: "Describe the printer's camera",
+		Query:   fieldsParams,
+		Result:  ref("Camera"),
+	},
+	"GET /api/v2/printers/:id/camera/snapshot": {
+		Summary:     "Get the latest frame from the printer's camera",
+		Description: "A JPEG unless `format=png` is given or the client only accepts PNG.",
+		Query:       append([]openAPIParam{{"format", "jpeg or png", "string"}}, snapshotParams...),
+		Produces:    map[string]interface{}{"image/jpeg": binarySchema, "image/png": binarySchema},
+	},
+	"GET /api/v2/printers/:id/operations": {
+		Summary: "List the printer's running and recently finished operations",
+		Query:   listParams,
+		Result:  listOf(ref("Operation")),
+	},
+	"GET /api/v2/discovered_printers": {
+		Summary: "Look for printers on the local network",
+		Query:   append([]openAPIParam{{"timeout", "How long to wait for answers, up to 30s", "string"}}, listParams...),
+		Result:  listOf(ref("DiscoveredPrinter")),
+	},
+	"GET /api/v2/operations": {
+		Summary: "List running and recently finished operations",
+		Query:   listParams,
+		Result:  listOf(ref("Operation")),
+	},
+	"GET /api/v2/operations/:operation_id": {
+		Summary: "Get an operation",
+		Query:   fieldsParams,
+		Result:  ref("Operation"),
+	},
+	"GET /api/v2/spools": {
+		Summary: "List every spool in the inventory",
+		Query:   listParams,
+		Result:  listOf(ref("Spool")),
+	},
+	"GET /api/v2/spools/:spool_id": {
+		Summary: "Get a spool",
+		Query:   fieldsParams,
+		Result:  ref("Spool"),
+	},
+	"POST /api/v2/spools": {
+		Summary: "Add a spool to the inventory",
+		Body:    map[string]interface{}{"application/json": ref("Spool")},
+		Result:  ref("Spool"),
+		Status:  http.StatusCreated,
+	},
+	"DELETE /api/v2/spools/:spool_id": {
+		Summary: "Remove a spool from the inventory",
+		Status:  http.StatusNoContent,
+	},
+}
+
+var fieldsParams = []openAPIParam{
+	{"fields", "Comma-separated list of the fields to include", "string"},
+}
+
+var listParams = append([]openAPIParam{
+	{"page", "The page to get, starting at 1", "integer"},
+	{"per_page", "How many items to get per page, up to 100", "integer"},
+}, fieldsParams...)
+
+// listOf is a page of a v2 collection of `schema`
+func listOf(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"items":    arrayOf(schema),
+			"page":     schemaOf("integer"),
+			"per_page": schemaOf("integer"),
+			"total":    schemaOf("integer"),
+		},
+	}
+}
+
+// v2Schema is the schema of a whole v2 response, which is just the result
+func v2Schema(result interface{}) interface{} {
+	return result
+}
+
+var v2ErrorSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"error": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"status":  schemaOf("integer"),
+				"message": schemaOf("string"),
+			},
+		},
+	},
+}
+
+var snapshotParams = []openAPIParam{
+	{"width", "Scale the image to this many pixels wide", "integer"},
+	{"quality", "JPEG quality, 1-100", "integer"},
+	{"rotate", "Rotate clockwise by 90, 180 or 270 degrees", "integer"},
+}
+
+// openAPISchemas are the components shared between routes of every API version
+var openAPISchemas = map[string]interface{}{
+	"Printer": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"machine_type":         schemaOf("string"),
+			"vid":                  schemaOf("integer"),
+			"ip":                   schemaOf("string"),
+			"pid":                  schemaOf("integer"),
+			"api_version":          schemaOf("string"),
+			"iserial":              schemaOf("string"),
+			"ssl_port":             schemaOf("string"),
+			"machine_name":         schemaOf("string"),
+			"motor_driver_version": schemaOf("string"),
+			"bot_type":             schemaOf("string"),
+			"port":                 schemaOf("string"),
+			"firmware_version":     schemaOf("object"),
+		},
+	},
+	"PrinterProcess": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":              schemaOf("integer"),
+			"name":            schemaOf("string"),
+			"step":            schemaOf("string"),
+			"progress":        schemaOf("integer"),
+			"reason":          map[string]interface{}{"type": "string", "nullable": true},
+			"cancellable":     schemaOf("boolean"),
+			"cancelled":       schemaOf("boolean"),
+			"complete":        schemaOf("boolean"),
+			"methods":         arrayOf(schemaOf("string")),
+			"elapsed_time":    schemaOf("integer"),
+			"time_estimation": schemaOf("integer"),
+			"filename":        schemaOf("string"),
+			"filepath":        schemaOf("string"),
+			"username":        schemaOf("string"),
+		},
+	},
+	"JobProgress": map[string]interface{}{
+		"allOf": []interface{}{
+			ref("PrinterProcess"),
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"started_at": map[string]interface{}{"type": "string", "format": "date-time", "nullable": true},
+					"eta":        map[string]interface{}{"type": "string", "format": "date-time", "nullable": true},
+					"history": arrayOf(map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"time":         map[string]interface{}{"type": "string", "format": "date-time"},
+							"step":         schemaOf("string"),
+							"progress":     schemaOf("integer"),
+							"elapsed_time": schemaOf("integer"),
+						},
+					}),
+				},
+			},
+		},
+	},
+	"Spool": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":               schemaOf("string"),
+			"material":         schemaOf("string"),
+			"color":            schemaOf("string"),
+			"vendor":           schemaOf("string"),
+			"initial_length":   map[string]interface{}{"type": "number", "description": "In millimeters"},
+			"remaining_length": map[string]interface{}{"type": "number", "description": "In millimeters"},
+			"printer":          map[string]interface{}{"type": "string", "description": "The ID of the printer the spool is loaded into"},
+			"tool_index":       schemaOf("integer"),
+		},
+	},
+	"Operation": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":         schemaOf("string"),
+			"type":       map[string]interface{}{"type": "string", "enum": []string{operationTypePrint, operationTypeLoadFilament, operationTypeUnloadFilament}},
+			"printer":    map[string]interface{}{"type": "string", "description": "The printer's ID"},
+			"status":     map[string]interface{}{"type": "string", "enum": []string{operationPending, operationRunning, operationSucceeded, operationFailed}},
+			"step":       schemaOf("string"),
+			"error":      schemaOf("string"),
+			"created_at": map[string]interface{}{"type": "string", "format": "date-time"},
+			"updated_at": map[string]interface{}{"type": "string", "format": "date-time"},
+			"transfer": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"bytes_sent": schemaOf("integer"),
+					"total":      schemaOf("integer"),
+					"rate":       map[string]interface{}{"type": "number", "description": "In bytes per second"},
+					"eta":        map[string]interface{}{"type": "number", "description": "Seconds until the transfer is done"},
+				},
+			},
+		},
+	},
+	"DiscoveredPrinter": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"ip":           schemaOf("string"),
+			"port":         schemaOf("string"),
+			"serial":       schemaOf("string"),
+			"machine_name": schemaOf("string"),
+			"config":       map[string]interface{}{"type": "object", "description": "A printer config entry for this printer"},
+		},
+	},
+	"V2Printer": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":              schemaOf("string"),
+			"name":            schemaOf("string"),
+			"serial":          schemaOf("string"),
+			"machine_name":    schemaOf("string"),
+			"connection_type": schemaOf("string"),
+			"tags":            arrayOf(schemaOf("string")),
+			"connected":       schemaOf("boolean"),
+			"revision":        schemaOf("integer"),
+			"info":            map[string]interface{}{"allOf": []interface{}{ref("Printer")}, "nullable": true},
+		},
+	},
+	"Tool": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"index":               schemaOf("integer"),
+			"current_temperature": schemaOf("integer"),
+			"target_temperature":  schemaOf("integer"),
+			"filament_present":    schemaOf("boolean"),
+			"tool_present":        schemaOf("boolean"),
+			"spool":               map[string]interface{}{"allOf": []interface{}{ref("Spool")}, "nullable": true},
+		},
+	},
+	"Camera": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"width":      schemaOf("integer"),
+			"height":     schemaOf("integer"),
+			"format":     map[string]interface{}{"type": "string", "enum": []string{"jpeg", "yuyv", "unknown"}},
+			"fetched_at": map[string]interface{}{"type": "string", "format": "date-time"},
+			"snapshot":   schemaOf("string"),
+		},
+	},
+	"PrintURLRequest": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url":     schemaOf("string"),
+			"headers": map[string]interface{}{"type": "object", "additionalProperties": schemaOf("string")},
+		},
+		"required": []string{"url"},
+	},
+}
+
+// envelope wraps `result` in apiResult, which every v1 JSON response comes in
+func envelope(result interface{}) interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"result": result,
+			"error":  map[string]interface{}{"type": "string", "nullable": true},
+		},
+	}
+}
+
+var v1ErrorSchema = envelope(map[string]interface{}{"nullable": true})
+
+// openAPIDocument builds the OpenAPI 3 document for the routes of API `version`.
+// `wrap` turns a route's Result into the schema of the whole response body.
+func openAPIDocument(version string, routes map[string]openAPIRoute, wrap func(result interface{}) interface{}, errorSchema interface{}) map[string]interface{} {
+	paths := map[string]map[string]interface{}{}
+
+	keys := []string{}
+	for key := range routes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		route := routes[key]
+		method, path := splitRouteKey(key)
+
+		params := []interface{}{}
+		segments := strings.Split(path, "/")
+		for i, s := range segments {
+			if strings.HasPrefix(s, ":") {
+				segments[i] = "{" + s[1:] + "}"
+				params = append(params, map[string]interface{}{"name": s[1:], "in": "path", "required": true, "schema": schemaOf("string")})
+			}
+		}
+
+		for _, q := range route.Query {
+			params = append(params, map[string]interface{}{"name": q.Name, "in": "query", "description": q.Description, "schema": schemaOf(q.Type)})
+		}
+
+		content := map[string]interface{}{"application/json": map[string]interface{}{"schema": wrap(route.Result)}}
+		if route.Produces != nil {
+			content = map[string]interface{}{}
+			for ct, schema := range route.Produces {
+				content[ct] = map[string]interface{}{"schema": schema}
+			}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		success := map[string]interface{}{"description": http.StatusText(status)}
+		if status != http.StatusNoContent {
+			success["content"] = content
+		}
+
+		op := map[string]interface{}{
+			"summary":    route.Summary,
+			"parameters": params,
+			"responses": map[string]interface{}{
+				strconv.Itoa(status): success,
+				"default": map[string]interface{}{
+					"description": "Error",
+					"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": errorSchema}},
+				},
+			},
+		}
+
+		if route.Description != "" {
+			op["description"] = route.Description
+		}
+
+		if route.Body != nil {
+			body := map[string]interface{}{}
+			for ct, schema := range route.Body {
+				body[ct] = map[string]interface{}{"schema": schema}
+			}
+			op["requestBody"] = map[string]interface{}{"required": true, "content": body}
+		}
+
+		p := strings.Join(segments, "/")
+		if paths[p] == nil {
+			paths[p] = map[string]interface{}{}
+		}
+		paths[p][strings.ToLower(method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "makerbotd",
+			"version": version,
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": openAPISchemas},
+	}
+}
+
+func splitRouteKey(key string) (method, path string) {
+	parts := strings.SplitN(key, " ", 2)
+	return parts[0], parts[1]
+}
+
+// documentedRouter remembers every route registered through it in `registered`,
+// so the OpenAPI document only lists the routes the configuration enabled.
+// openapi_test.go checks that every route that can be registered is documented.
+type documentedRouter struct {
+	*instrumentedRouter
+	registered *[]string
+}
+
+func (dr *documentedRouter) Handle(method, path string, handle httprouter.Handle) {
+	*dr.registered = append(*dr.registered, method+" "+path)
+	dr.instrumentedRouter.Handle(method, path, handle)
+}
+
+func (dr *documentedRouter) GET(path string, handle httprouter.Handle) {
+	dr.Handle("GET", path, handle)
+}
+
+func (dr *documentedRouter) POST(path string, handle httprouter.Handle) {
+	dr.Handle("POST", path, handle)
+}
+
+func (dr *documentedRouter) PUT(path string, handle httprouter.Handle) {
+	dr.Handle("PUT", path, handle)
+}
+
+func (dr *documentedRouter) DELETE(path string, handle httprouter.Handle) {
+	dr.Handle("DELETE", path, handle)
+}
+
+// registeredRoutes picks the documentation of the `registered` routes out of `docs`
+func registeredRoutes(docs map[string]openAPIRoute, registered []string) map[string]openAPIRoute {
+	routes := map[string]openAPIRoute{}
+	for _, key := range registered {
+		if route, ok := docs[key]; ok {
+			routes[key] = route
+		}
+	}
+
+	return routes
+}
+
+func (a *APIv1) getOpenAPI(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	enc.Encode(openAPIDocument("1", registeredRoutes(v1Routes, a.routes), envelope, v1ErrorSchema))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+// checkDocumented compares the routes an API registered with the ones documented
+// in openapi.go, in both directions
+func checkDocumented(t *testing.T, api string, registered []string, docs map[string]openAPIRoute) {
+	seen := map[string]bool{}
+	for _, key := range registered {
+		seen[key] = true
+
+		if _, ok := docs[key]; !ok {
+			t.Errorf("%s route %s is not documented in openapi.go", api, key)
+		}
+	}
+
+	for key := range docs {
+		if !seen[key] {
+			t.Errorf("%s route %s is documented in openapi.go but never registered", api, key)
+		}
+	}
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	ctx := newTestContext(t, &config{AdminToken: "admin", OctoPrintAPI: true})
+	router := newInstrumentedRouter()
+
+	v1 := &APIv1{context: ctx}
+	v1.Route(router)
+
+	v2 := &APIv2{context: ctx}
+	v2.Route(router)
+
+	(&APIOctoPrint{context: ctx}).Route(router)
+
+	checkDocumented(t, "v1", v1.routes, v1Routes)
+	checkDocumented(t, "v2", v2.routes, v2Routes)
+}
+
+func TestOpenAPIDocumentListsRegisteredRoutes(t *testing.T) {
+	ctx := newTestContext(t, &config{ReadOnly: true})
+	router := getRouter(ctx)
+
+	for _, path := range []string{"/api/v1/openapi.json", "/api/v2/openapi.json"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+
+		var doc struct {
+			Paths map[string]map[string]interface{} `json:"paths"`
+		}
+
+		if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+
+		methods := []string{}
+		for _, operations := range doc.Paths {
+			for method := range operations {
+				methods = append(methods, method)
+			}
+		}
+
+		if len(methods) == 0 {
+			t.Errorf("%s documents no routes", path)
+		}
+
+		for _, method := range methods {
+			if method != "get" {
+				t.Errorf("%s documents a %s route, but makerbotd is read-only", path, method)
+			}
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+const (
+	operationPending   = "pending"
+	operationRunning   = "running"
+	operationSucceeded = "succeeded"
+	operationFailed    = "failed"
+
+	operationTypePrint          = "print"
+	operationTypeLoadFilament   = "load_filament"
+	operationTypeUnloadFilament = "unload_filament"
+
+	// operationRetention is how long finished operations can still be looked up
+	operationRetention = time.Hour
+	// transferUpdateInterval limits how often transfer progress is sent to subscribers
+	transferUpdateInterval = 250 * time.Millisecond
+	// processStartTimeout is how long an operation waits for the printer to start a process for it
+	processStartTimeout = 30 * time.Second
+)
+
+type transferProgress struct {
+	BytesSent int64    `json:"bytes_sent"`
+	Total     int64    `json:"total"`
+	Rate      float64  `json:"rate"`          // Rate is in bytes per second
+	ETA       *float64 `json:"eta,omitempty"` // ETA is the number of seconds until the transfer is done
+}
+
+// operation is something makerbotd is doing on a printer that takes a while
+type operation struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Printer   string            `json:"printer"`
+	Status    string            `json:"status"`
+	Step      string            `json:"step,omitempty"` // Step is the step the printer's process for this operation is on
+	Error     string            `json:"error,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Transfer  *transferProgress `json:"transfer,omitempty"`
+}
+
+// Done returns true if the operation succeeded or failed
+func (op *operation) Done() bool {
+	return op.Status == operationSucceeded || op.Status == operationFailed
+}
+
+// operations keeps track of every running operation and recently finished ones.
+// Operations are only changed through it, so subscribers get every update.
+type operations struct {
+	sync.Mutex
+	ops         map[string]*operation
+	subscribers map[string][]chan operation
+}
+
+func newOperations() *operations {
+	return &operations{
+		ops:         map[string]*operation{},
+		subscribers: map[string][]chan operation{},
+	}
+}
+
+// prune forgets operations that finished a while ago. It must be called with ops locked.
+func (ops *operations) prune() {
+	for id, op := range ops.ops {
+		if op.Done() && time.Since(op.UpdatedAt) > operationRetention {
+			delete(ops.ops, id)
+		}
+	}
+}
+
+// Start registers a new operation of `kind` on `printer`
+func (ops *operations) Start(kind, printer string) operation {
+	ops.Lock()
+	defer ops.Unlock()
+
+	ops.prune()
+
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	now := time.Now()
+	op := &operation{
+		ID:        hex.EncodeToString(id),
+		Type:      kind,
+		Printer:   printer,
+		Status:    operationPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	ops.ops[op.ID] = op
+
+	return *op
+}
+
+// Update changes an operation with `f` and lets subscribers know
+func (ops *operations) Update(id string, f func(op *operation)) {
+	ops.Lock()
+	defer ops.Unlock()
+
+	op, ok := ops.ops[id]
+	if !ok {
+		return
+	}
+
+	f(op)
+	op.UpdatedAt = time.Now()
+
+	for _, ch := range ops.subscribers[id] {
+		// Subscribers only care about the latest state, so drop what they haven't read yet
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- *op
+	}
+
+	if op.Done() {
+		for _, ch := range ops.subscribers[id] {
+			close(ch)
+		}
+		delete(ops.subscribers, id)
+	}
+}
+
+// Finish marks an operation as succeeded, or failed if `err` is not nil
+func (ops *operations) Finish(id string, err error) {
+	ops.Update(id, func(op *operation) {
+		if err != nil {
+			op.Status = operationFailed
+			op.Error = err.Error()
+			return
+		}
+
+		op.Status = operationSucceeded
+	})
+}
+
+func (ops *operations) Get(id string) (operation, bool) {
+	ops.Lock()
+	defer ops.Unlock()
+
+	op, ok := ops.ops[id]
+	if !ok {
+		return operation{}, false
+	}
+
+	return *op, true
+}
+
+// List returns every known operation, optionally only those on `printer`, newest first
+func (ops *operations) List(printer string) []operation {
+	ops.Lock()
+	defer ops.Unlock()
+
+	list := []operation{}
+	for _, op := range ops.ops {
+		if printer == "" || op.Printer == printer {
+			list = append(list, *op)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+
+	return list
+}
+
+// Subscribe returns a channel that receives the operation's state whenever it
+// changes. It is closed once the operation is done. The returned function must be
+// called to unsubscribe early.
+func (ops *operations) Subscribe(id string) (<-chan operation, func(), bool) {
+	ops.Lock()
+	defer ops.Unlock()
+
+	op, ok := ops.ops[id]
+	if !ok {
+		return nil, nil, false
+	}
+
+	ch := make(chan operation, 1)
+	ch <- *op
+
+	if op.Done() {
+		close(ch)
+		return ch, func() {}, true
+	}
+
+	ops.subscribers[id] = append(ops.subscribers[id], ch)
+
+	unsubscribe := func() {
+		ops.Lock()
+		defer ops.Unlock()
+
+		subs := ops.subscribers[id]
+		for i, s := range subs {
+			if s == ch {
+				ops.subscribers[id] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return ch, unsubscribe, true
+}
+
+// transferReader reports how much of a print file has been sent to the printer
+type transferReader struct {
+	r          io.Reader
+	ops        *operations
+	id         string
+	total      int64
+	sent       int64
+	started    time.Time
+	lastUpdate time.Time
+}
+
+// newTransferReader reads `file` for operation `id`, starting the operation's
+// transfer progress at 0
+func newTransferReader(ops *operations, id string, file *os.File, size int64) *transferReader {
+	tr := &transferReader{r: file, ops: ops, id: id, total: size, started: time.Now()}
+	tr.report()
+
+	return tr
+}
+
+func (tr *transferReader) Read(p []byte) (int, error) {
+	n, err := tr.r.Read(p)
+	tr.sent += int64(n)
+
+	if time.Since(tr.lastUpdate) >= transferUpdateInterval || (n > 0 && tr.sent == tr.total) {
+		tr.lastUpdate = time.Now()
+		tr.report()
+	}
+
+	return n, err
+}
+
+func (tr *transferReader) report() {
+	progress := transferProgress{BytesSent: tr.sent, Total: tr.total}
+
+	// The first few reads usually come out of buffers, which would make the rate look way too high
+	if elapsed := time.Since(tr.started); elapsed >= transferUpdateInterval {
+		progress.Rate = float64(tr.sent) / elapsed.Seconds()
+	}
+
+	if progress.Rate > 0 {
+		eta := float64(tr.total-tr.sent) / progress.Rate
+		progress.ETA = &eta
+	}
+
+	tr.ops.Update(tr.id, func(op *operation) {
+		op.Status = operationRunning
+		op.Transfer = &progress
+	})
+}
+
+// currentProcessID returns the ID of the printer's current process, or -1 if it has none
+func (pc *printerConnection) currentProcessID() int {
+	if proc := pc.currentProcess(); proc != nil {
+		return proc.ID
+	}
+
+	return -1
+}
+
+// currentProcess returns the printer's current process, or nil if it has none
+// or isn't connected
+func (pc *printerConnection) currentProcess() *makerbot.PrinterProcess {
+	conn := pc.backend()
+	if conn == nil {
+		return nil
+	}
+
+	printer := conn.Printer()
+	if printer == nil || printer.Metadata == nil {
+		return nil
+	}
+
+	return printer.Metadata.CurrentProcess
+}
+
+// processError returns why a finished process failed, or nil if it didn't
+func processError(proc *makerbot.PrinterProcess) error {
+	switch {
+	case proc.Cancelled:
+		return errors.New("cancelled")
+	case proc.Reason != nil:
+		return errors.New(*proc.Reason)
+	case proc.Step == "failed" || proc.Step == "error":
+		return errors.New("the printer reported an error")
+	}
+
+	return nil
+}
+
+// followProcess keeps an operation up to date with the process the printer
+// started for it, and finishes the operation when that process ends. Processes
+// with the ID `before` were already running and are ignored.
+func (pc *printerConnection) followProcess(id string, before int) {
+	ops := pc.context.Operations
+	deadline := time.Now().Add(processStartTimeout)
+	following := -1
+
+	for {
+		rev, _ := pc.Revision()
+
+		if pc.backend() == nil {
+			ops.Finish(id, errors.New("printer disconnected"))
+			return
+		}
+
+		proc := pc.currentProcess()
+		switch {
+		case proc != nil && proc.ID != before && (following == -1 || proc.ID == following):
+			following = proc.ID
+			ops.Update(id, func(op *operation) {
+				op.Status = operationRunning
+				op.Step = proc.Step
+			})
+
+			if proc.Complete {
+				ops.Finish(id, processError(proc))
+				return
+			}
+		case following != -1:
+			// The process went away without us seeing how it ended
+			ops.Finish(id, nil)
+			return
+		case time.Now().After(deadline):
+			// Nothing to follow, so the command is as done as it will get
+			ops.Finish(id, nil)
+			return
+		}
+
+		wait := maxStateWait
+		if following == -1 {
+			wait = time.Until(deadline)
+		}
+
+		pc.WaitForRevision(rev, wait, nil)
+		if pc.context.ShuttingDown() {
+			return
+		}
+	}
+}
+
+// StartProcess tracks `command`, which makes the printer start a process, as an
+// operation of `kind`, and returns the operation right away. `command` runs in
+// the background on the printer's backend, and then the operation follows the
+// process it started. If `command` fails, so does the operation.
+func (pc *printerConnection) StartProcess(kind string, command func(conn printerBackend) error) operation {
+	ops := pc.context.Operations
+	id := ops.Start(kind, pc.ID()).ID
+	before := pc.currentProcessID()
+
+	go func() {
+		conn := pc.backend()
+		if conn == nil {
+			ops.Finish(id, errPrinterNotConnected)
+			return
+		}
+
+		err := command(conn)
+		if err != nil {
+			ops.Finish(id, err)
+			return
+		}
+
+		pc.followProcess(id, before)
+	}()
+
+	op, _ := ops.Get(id)
+	return op
+}
+
+// Print sends a spooled print file to the printer in the background, and returns
+// the operation tracking the transfer and the print itself right away. The
+// operation takes over `file` and removes it once it has been sent.
+func (pc *printerConnection) Print(name string, file *os.File, size int64) operation {
+	ops := pc.context.Operations
+	id := ops.Start(operationTypePrint, pc.ID()).ID
+
+	go pc.transferPrint(newTransferReader(ops, id, file, size), name, file)
+
+	op, _ := ops.Get(id)
+	return op
+}
+
+// transferPrint sends a print file to the printer through `tr` and follows the
+// print until the printer is done with it. It takes the file over.
+func (pc *printerConnection) transferPrint(tr *transferReader, name string, file *os.File) {
+	ops, id := tr.ops, tr.id
+	before := pc.currentProcessID()
+
+	conn := pc.backend()
+	if conn == nil {
+		discardUpload(file)
+		ops.Finish(id, errPrinterNotConnected)
+		return
+	}
+
+	err := conn.Print(name, tr, int(tr.total))
+	discardUpload(file)
+
+	if err != nil {
+		ops.Finish(id, err)
+		return
+	}
+
+	pc.followProcess(id, before)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+func TestPrintRunsInBackground(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	pc, fb := addFakePrinter(t, ctx, printerConfig{Name: "fake", ID: "1"})
+
+	hold := make(chan struct{})
+	fb.mu.Lock()
+	fb.hold = hold
+	fb.mu.Unlock()
+
+	data := bytes.Repeat([]byte("G1 X0\n"), 1000)
+	file, size, err := spoolUpload(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Print must return while the printer is still holding up the transfer
+	op := pc.Print("part.gcode", file, size)
+	if op.Done() || op.Transfer == nil || op.Transfer.Total != size {
+		t.Fatalf("got operation %+v, want a pending transfer of %d bytes", op, size)
+	}
+
+	close(hold)
+
+	waitFor(t, func() bool {
+		printed, ok := fb.Printed("part.gcode")
+		return ok && bytes.Equal(printed, data)
+	})
+
+	waitFor(t, func() bool {
+		_, err := os.Stat(file.Name())
+		return os.IsNotExist(err)
+	})
+
+	waitFor(t, func() bool {
+		op, _ := ctx.Operations.Get(op.ID)
+		return op.Status == operationRunning && op.Step == "printing"
+	})
+}
+
+func TestPrintFailsWhenDisconnected(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	pc, _ := addFakePrinter(t, ctx, printerConfig{Name: "fake", ID: "1"})
+
+	pc.mu.Lock()
+	pc.connection = nil
+	pc.Connected = false
+	pc.mu.Unlock()
+
+	file, size, err := spoolUpload(bytes.NewReader([]byte("G1 X0\n")), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op := pc.Print("part.gcode", file, size)
+
+	waitFor(t, func() bool {
+		op, _ := ctx.Operations.Get(op.ID)
+		return op.Status == operationFailed && op.Error == errPrinterNotConnected.Error()
+	})
+
+	if _, err := os.Stat(file.Name()); !os.IsNotExist(err) {
+		t.Error("the print file was left behind")
+	}
+}
+
+func TestLoadFilamentRunsInBackground(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	_, fb := addFakePrinter(t, ctx, printerConfig{Name: "fake", ID: "1"})
+
+	s, err := ctx.Spools.Add(spool{Material: "PLA", InitialLength: 1000, RemainingLength: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fb.setState(func(m *makerbot.PrinterMetadata) {
+		m.Toolheads.Extruder = []makerbot.ToolheadMetadata{{ToolPresent: true}}
+	})
+
+	hold := make(chan struct{})
+	fb.mu.Lock()
+	fb.hold = hold
+	fb.mu.Unlock()
+
+	router := newInstrumentedRouter()
+	(&APIv2{context: ctx}).Route(router)
+
+	req := httptest.NewRequest("PUT", "/api/v2/printers/fake/tools/0/filament", bytes.NewBufferString(`{"spool":"`+s.ID+`"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	// The response must be written while the printer is still loading
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("got %d %s, want 202", rec.Code, rec.Body)
+	}
+
+	var op operation
+	if err := json.NewDecoder(rec.Body).Decode(&op); err != nil || op.Done() {
+		t.Fatalf("got operation %+v (%v), want one that is still going", op, err)
+	}
+
+	if got, _ := ctx.Spools.Get(s.ID); got.Printer != "" {
+		t.Error("the spool was assigned before the filament was loaded")
+	}
+
+	close(hold)
+
+	waitFor(t, func() bool {
+		got, _ := ctx.Spools.Get(s.ID)
+		return got.Printer == "1" && got.ToolIndex != nil && *got.ToolIndex == 0
+	})
+}
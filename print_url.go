@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPrintURLMaxSize = 500 * 1024 * 1024
+	printURLTimeout        = 10 * time.Minute
+)
+
+var (
+	errHostNotAllowed = errors.New("downloading prints from this host is not allowed")
+	errBadPrintURL    = errors.New("url must be an http or https URL")
+)
+
+type printURLRequest struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"` // Headers are sent along with the download request, e.g. for authentication
+}
+
+// hostAllowed checks `host` against PrintURLAllowedHosts. Entries can be exact
+// host names or start with "*." to allow every subdomain.
+func (ctx *mbContext) hostAllowed(host string) bool {
+	host = strings.ToLower(host)
+
+	for _, allowed := range ctx.Config.PrintURLAllowedHosts {
+		allowed = strings.ToLower(allowed)
+
+		if allowed == host {
+			return true
+		}
+
+		if strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkPrintURL checks that `raw` is an http or https URL on an allowed host
+func (ctx *mbContext) checkPrintURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errBadPrintURL
+	}
+
+	if !ctx.hostAllowed(u.Hostname()) {
+		return nil, errHostNotAllowed
+	}
+
+	return u, nil
+}
+
+// setPrintURLHeaders adds the PrintURLHeaders configured for the request's host
+func (ctx *mbContext) setPrintURLHeaders(r *http.Request) {
+	for k, v := range ctx.Config.PrintURLHeaders[strings.ToLower(r.URL.Hostname())] {
+		r.Header.Set(k, v)
+	}
+}
+
+// downloadPrintFile downloads a print file into a temporary file, enforcing the
+// allowed host list and maximum size. The caller must close and remove the file.
+func (ctx *mbContext) downloadPrintFile(req printURLRequest) (file *os.File, name string, size int64, err error) {
+	u, err := ctx.checkPrintURL(req.URL)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	maxSize := ctx.Config.PrintURLMaxSize
+	if maxSize <= 0 {
+		maxSize = defaultPrintURLMaxSize
+	}
+
+	client := http.Client{
+		Timeout: printURLTimeout,
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("too many redirects")
+			}
+
+			if !ctx.hostAllowed(r.URL.Hostname()) {
+				return errHostNotAllowed
+			}
+
+			// Redirects carry over the first request's headers, but the ones meant
+			// for its host shouldn't be sent anywhere else
+			if first := via[0].URL; !strings.EqualFold(r.URL.Hostname(), first.Hostname()) {
+				for k := range ctx.Config.PrintURLHeaders[strings.ToLower(first.Hostname())] {
+					r.Header.Del(k)
+				}
+
+				for k := range req.Headers {
+					r.Header.Del(k)
+				}
+
+				ctx.setPrintURLHeaders(r)
+			}
+
+			return nil
+		},
+	}
+
+	hreq, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	ctx.setPrintURLHeaders(hreq)
+
+	for k, v := range req.Headers {
+		hreq.Header.Set(k, v)
+	}
+
+	res, err := client.Do(hreq)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, "", 0, fmt.Errorf("downloading print file failed: %s", res.Status)
+	}
+
+	if res.ContentLength > maxSize {
+		return nil, "", 0, fmt.Errorf("print file is larger than %d bytes", maxSize)
+	}
+
+	name = path.Base(res.Request.URL.Path)
+	if _, params, err := mime.ParseMediaType(res.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		name = path.Base(params["filename"])
+	}
+
+	file, err = ioutil.TempFile("", "makerbotd-print-")
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	size, err = io.Copy(file, io.LimitReader(res.Body, maxSize+1))
+	if err == nil && size > maxSize {
+		err = fmt.Errorf("print file is larger than %d bytes", maxSize)
+	}
+
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, "", 0, err
+	}
+
+	return file, name, size, nil
+}
+
+// PrintURL downloads a print file and sends it to the printer in the
+// background, as one operation so the caller doesn't have to wait for the
+// download. The URL should already have been checked with checkPrintURL.
+func (pc *printerConnection) PrintURL(req printURLRequest) operation {
+	ctx := pc.context
+	ops := ctx.Operations
+	id := ops.Start(operationTypePrint, pc.ID()).ID
+
+	go func() {
+		file, name, size, err := ctx.downloadPrintFile(req)
+		if err != nil {
+			ops.Finish(id, err)
+			return
+		}
+
+		warning, err := ctx.preparePrint(pc, file, size)
+		if err != nil {
+			ops.Finish(id, err)
+			return
+		}
+
+		// There's no response left to warn in
+		if warning != "" {
+			ctx.Debugf("printerConnection: printing %s anyway: %s\n", name, warning)
+		}
+
+		pc.transferPrint(newTransferReader(ops, id, file, size), path.Base(name), file)
+	}()
+
+	op, _ := ops.Get(id)
+	return op
+}
+
+// printFromURL handles a print request with a JSON body pointing to the file to print
+func (a *APIv1) printFromURL(w http.ResponseWriter, r *http.Request, printer *printerConnection) {
+	var req printURLRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.URL == "" {
+		a.badRequest(w, r)
+		return
+	}
+
+	if len(a.context.Config.PrintURLAllowedHosts) == 0 {
+		a.forbidden(w, r)
+		return
+	}
+
+	_, err = a.context.checkPrintURL(req.URL)
+	if err == errHostNotAllowed {
+		a.forbidden(w, r)
+		return
+	}
+
+	if err != nil {
+		a.badRequest(w, r)
+		return
+	}
+
+	a.accepted(w, r, printer.PrintURL(req))
+}
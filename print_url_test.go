@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"makerbotd/makerbotdpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startPrintFileServer serves a print file at /part.makerbot and redirects
+// /elsewhere to the same server by a host name that isn't allowed
+func startPrintFileServer(t *testing.T) (allowedURL, redirectURL string) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/part.makerbot":
+			w.Header().Set("Content-Disposition", `attachment; filename="renamed.makerbot"`)
+			w.Write([]byte(strings.Repeat("x", 100)))
+		case "/elsewhere":
+			_, port, _ := net.SplitHostPort(r.Host)
+			http.Redirect(w, r, "http://localhost:"+port+"/part.makerbot", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv.URL + "/part.makerbot", srv.URL + "/elsewhere"
+}
+
+func TestDownloadPrintFile(t *testing.T) {
+	allowedURL, redirectURL := startPrintFileServer(t)
+	ctx := newTestContext(t, &config{PrintURLAllowedHosts: []string{"127.0.0.1"}})
+
+	file, name, size, err := ctx.downloadPrintFile(printURLRequest{URL: allowedURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	if name != "renamed.makerbot" || size != 100 {
+		t.Errorf("downloaded %s (%d bytes), want renamed.makerbot (100 bytes)", name, size)
+	}
+
+	_, _, _, err = ctx.downloadPrintFile(printURLRequest{URL: redirectURL})
+	if !errors.Is(err, errHostNotAllowed) {
+		t.Errorf("redirect to a host that isn't allowed returned %v", err)
+	}
+
+	ctx.Config.PrintURLMaxSize = 10
+	_, _, _, err = ctx.downloadPrintFile(printURLRequest{URL: allowedURL})
+	if err == nil {
+		t.Error("expected a file over PrintURLMaxSize to be refused")
+	}
+}
+
+func TestPrintURLHostNotAllowed(t *testing.T) {
+	ctx := newTestContext(t, &config{PrintURLAllowedHosts: []string{"127.0.0.1"}})
+	addFakePrinter(t, ctx, printerConfig{Name: "fake", ID: "1"})
+
+	router := newInstrumentedRouter()
+	(&APIv1{context: ctx}).Route(router)
+	(&APIv2{context: ctx}).Route(router)
+
+	for _, path := range []string{"/api/v1/printers/fake/prints", "/api/v2/printers/fake/jobs"} {
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{"url": "http://example.com/part.makerbot"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s: got %d, want 403", path, rec.Code)
+		}
+	}
+
+	s := &grpcServer{context: ctx}
+	_, err := s.PrintURL(context.Background(), &makerbotdpb.PrintURLRequest{PrinterId: "fake", Url: "http://example.com/part.makerbot"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("gRPC: got %v, want PermissionDenied", err)
+	}
+}
+
+func TestPrintURLInBackground(t *testing.T) {
+	allowedURL, redirectURL := startPrintFileServer(t)
+	ctx := newTestContext(t, &config{PrintURLAllowedHosts: []string{"127.0.0.1"}})
+	printer, fb := addFakePrinter(t, ctx, printerConfig{Name: "fake", ID: "1"})
+
+	failed := printer.PrintURL(printURLRequest{URL: redirectURL})
+	waitFor(t, func() bool {
+		op, _ := ctx.Operations.Get(failed.ID)
+		return op.Status == operationFailed && strings.Contains(op.Error, errHostNotAllowed.Error())
+	})
+
+	op := printer.PrintURL(printURLRequest{URL: allowedURL})
+	if op.Type != operationTypePrint || op.Done() {
+		t.Errorf("got operation %+v, want a print that is still going", op)
+	}
+
+	waitFor(t, func() bool {
+		printed, ok := fb.Printed("renamed.makerbot")
+		return ok && len(printed) == 100
+	})
+}
+
+func TestPrintURLRedirectHeaders(t *testing.T) {
+	seen := make(chan http.Header, 2)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen <- r.Header.Clone()
+
+		if r.URL.Path == "/elsewhere" {
+			_, port, _ := net.SplitHostPort(r.Host)
+			http.Redirect(w, r, "http://localhost:"+port+"/part.makerbot", http.StatusFound)
+			return
+		}
+
+		w.Write([]byte("part"))
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx := newTestContext(t, &config{
+		PrintURLAllowedHosts: []string{"127.0.0.1", "localhost"},
+		PrintURLHeaders: map[string]map[string]string{
+			"127.0.0.1": {"X-Token": "first"},
+			"localhost": {"X-Other-Token": "second"},
+		},
+	})
+
+	file, _, _, err := ctx.downloadPrintFile(printURLRequest{URL: srv.URL + "/elsewhere", Headers: map[string]string{"X-Request": "yes"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	discardUpload(file)
+
+	if first := <-seen; first.Get("X-Token") != "first" || first.Get("X-Request") != "yes" {
+		t.Errorf("first request had headers %v, want X-Token and X-Request", first)
+	}
+
+	if second := <-seen; second.Get("X-Token") != "" || second.Get("X-Request") != "" || second.Get("X-Other-Token") != "second" {
+		t.Errorf("redirected request had headers %v, want only X-Other-Token", second)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+// maxStateWait is the longest a long-polling request can wait for a state change
+const maxStateWait = 2 * time.Minute
+
+// revisionEpoch identifies this run of makerbotd. Revisions start over when
+// makerbotd restarts, so it is part of every ETag made from one.
+var revisionEpoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+// revisionETag is the ETag of the printer state at `rev`
+func revisionETag(rev uint64) string {
+	return `"` + revisionEpoch + "-" + strconv.FormatUint(rev, 10) + `"`
+}
+
+// bumpRevision records that the printer's state changed and wakes up everyone
+// waiting for a change
+func (pc *printerConnection) bumpRevision() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.revision++
+
+	pc.state = nil
+	if pc.Connected && pc.connection != nil {
+		pc.state = snapshotPrinter(pc.connection.Printer())
+	}
+
+	if pc.changed != nil {
+		close(pc.changed)
+	}
+	pc.changed = make(chan struct{})
+}
+
+// snapshotPrinter copies the printer's info and state, so the backend changing
+// its own copy later doesn't change a revision that was already handed out
+func snapshotPrinter(p *makerbot.Printer) *makerbot.Printer {
+	if p == nil {
+		return nil
+	}
+
+	snapshot := *p
+	if p.Metadata != nil {
+		md := *p.Metadata
+		if md.CurrentProcess != nil {
+			proc := *md.CurrentProcess
+			md.CurrentProcess = &proc
+		}
+		snapshot.Metadata = &md
+	}
+
+	return &snapshot
+}
+
+// Revision returns the current state revision, and a channel that is closed
+// when it changes
+func (pc *printerConnection) Revision() (uint64, <-chan struct{}) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.changed == nil {
+		pc.changed = make(chan struct{})
+	}
+
+	return pc.revision, pc.changed
+}
+
+// State returns the current state revision along with the printer's info and
+// state as of that revision. The printer is nil if it wasn't connected.
+func (pc *printerConnection) State() (uint64, *makerbot.Printer) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	return pc.revision, pc.state
+}
+
+// WaitForRevision blocks until the state revision is different from `since`,
+// `timeout` passes or `cancel` is closed. It returns the current revision.
+// Revisions newer than the current one are from before makerbotd restarted, so
+// they don't wait at all.
+func (pc *printerConnection) WaitForRevision(since uint64, timeout time.Duration, cancel <-chan struct{}) uint64 {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		rev, changed := pc.Revision()
+		if rev != since {
+			return rev
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return rev
+		case <-cancel:
+			return rev
+		case <-pc.context.done:
+			return rev
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+func TestPrinterETag(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	_, fb := addFakePrinter(t, ctx, printerConfig{Name: "fake", ID: "1"})
+
+	router := newInstrumentedRouter()
+	(&APIv1{context: ctx}).Route(router)
+
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/printers/fake", nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	res := get(nil)
+	etag := res.Header().Get("ETag")
+	if res.Code != http.StatusOK || !strings.Contains(etag, revisionEpoch) {
+		t.Fatalf("got %d with ETag %s, want 200 with an ETag from this run", res.Code, etag)
+	}
+
+	if res = get(http.Header{"If-None-Match": {etag}}); res.Code != http.StatusNotModified {
+		t.Errorf("got %d for a current ETag, want 304", res.Code)
+	}
+
+	// An ETag for the same revision from before a restart must not match
+	stale := `"previousrun-` + res.Header().Get("X-Revision") + `"`
+	if res = get(http.Header{"If-None-Match": {stale}}); res.Code != http.StatusOK {
+		t.Errorf("got %d for an ETag from before a restart, want 200", res.Code)
+	}
+
+	fb.setState(func(m *makerbot.PrinterMetadata) {
+		m.CurrentProcess = &makerbot.PrinterProcess{ID: 1, Name: "PrintProcess", Step: "printing"}
+	})
+
+	res = get(http.Header{"If-None-Match": {etag}})
+	if res.Code != http.StatusOK || res.Header().Get("ETag") == etag {
+		t.Errorf("got %d with ETag %s after a state change, want 200 with a new ETag", res.Code, res.Header().Get("ETag"))
+	}
+}
+
+func TestWaitForRevisionAfterRestart(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	pc, _ := addFakePrinter(t, ctx, printerConfig{ID: "1"})
+
+	rev, _ := pc.Revision()
+
+	start := time.Now()
+	if got := pc.WaitForRevision(rev+100, time.Second, nil); got != rev {
+		t.Errorf("WaitForRevision() = %d, want %d", got, rev)
+	}
+
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("waiting for a revision from before a restart blocked")
+	}
+}
+
+func TestStateIsASnapshot(t *testing.T) {
+	ctx := newTestContext(t, &config{})
+	pc, fb := addFakePrinter(t, ctx, printerConfig{ID: "1"})
+
+	fb.setState(func(m *makerbot.PrinterMetadata) {
+		m.CurrentProcess = &makerbot.PrinterProcess{ID: 1, Name: "PrintProcess", Step: "printing", Progress: 10}
+	})
+
+	_, state := pc.State()
+
+	// Change the backend's copy in place, without a new revision
+	fb.mu.Lock()
+	fb.printer.MachineName = "changed"
+	fb.printer.Metadata.CurrentProcess.Progress = 50
+	fb.mu.Unlock()
+
+	if state.MachineName == "changed" || state.Metadata.CurrentProcess.Progress != 10 {
+		t.Errorf("the state handed out changed along with the backend: %+v", state.Metadata.CurrentProcess)
+	}
+}
@@ -1,46 +1,18 @@
 package main
 
 import (
-	"encoding/json"
 	"net/http"
 	"net/http/pprof"
-	"runtime"
-
-	"github.com/julienschmidt/httprouter"
 )
 
-type statsResponse struct {
-	NumGoroutine     int
-	NumCPU           int
-	AllocMemory      uint64
-	TotalAllocMemory uint64
-	SysMemory        uint64
-}
-
-func stats(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	w.Header().Set("Content-Type", "application/json")
-
-	var ms runtime.MemStats
-	runtime.ReadMemStats(&ms)
-
-	res := statsResponse{
-		NumGoroutine:     runtime.NumGoroutine(),
-		NumCPU:           runtime.NumCPU(),
-		AllocMemory:      ms.Alloc,
-		TotalAllocMemory: ms.TotalAlloc,
-		SysMemory:        ms.Sys,
-	}
-
-	enc := json.NewEncoder(w)
-	enc.Encode(apiSuccess(res))
-}
+func getRouter(ctx *mbContext) http.Handler {
+	router := newInstrumentedRouter()
 
-func getRouter(ctx *mbContext) *httprouter.Router {
-	router := httprouter.New()
+	router.GET("/healthz", healthz(ctx))
+	router.GET("/readyz", readyz(ctx))
+	router.GET("/metrics", metricsHandler(ctx))
 
 	if ctx.Config.Debug {
-		router.GET("/_/stats", stats)
-
 		router.HandlerFunc("GET", "/debug/pprof/", pprof.Index)
 		router.HandlerFunc("GET", "/debug/pprof/cmdline", pprof.Cmdline)
 		router.HandlerFunc("GET", "/debug/pprof/profile", pprof.Profile)
@@ -53,8 +25,16 @@ func getRouter(ctx *mbContext) *httprouter.Router {
 		router.Handler("GET", "/debug/pprof/block", pprof.Handler("block"))
 	}
 
-	v1 := APIv1{ctx}
+	v1 := APIv1{context: ctx}
 	v1.Route(router)
 
-	return router
+	v2 := APIv2{context: ctx}
+	v2.Route(router)
+
+	if ctx.Config.OctoPrintAPI {
+		op := APIOctoPrint{context: ctx}
+		op.Route(router)
+	}
+
+	return ctx.Metrics.instrument(router)
 }
@@ -0,0 +1,557 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+const (
+	simFrameWidth  = 320
+	simFrameHeight = 240
+
+	simAccessToken = "simulated-access-token"
+)
+
+type simUpload struct {
+	name   string
+	length int
+	data   bytes.Buffer
+}
+
+// simPrinter is a fake MakerBot that speaks enough of the printer's JSON-RPC
+// protocol for makerbot-rpc (and therefore makerbotd) to drive it like a real
+// printer on the local network
+type simPrinter struct {
+	sync.Mutex
+	info      makerbot.Printer
+	metadata  makerbot.PrinterMetadata
+	speed     int
+	nextID    int
+	clients   map[*simClient]bool
+	uploads   map[string]*simUpload
+	files     map[string]int
+	suspended bool
+}
+
+type simClient struct {
+	sync.Mutex
+	conn       net.Conn
+	authorized bool     // authorized is guarded by the simPrinter's lock
+	queue      [][]byte // queue is written to conn in order by flush
+	flushing   bool
+}
+
+func newSimPrinter(name, serial, ip, port string, speed int) *simPrinter {
+	sp := &simPrinter{
+		info: makerbot.Printer{
+			MachineType:        "platypus",
+			Vid:                9153,
+			IP:                 ip,
+			Pid:                5,
+			APIVersion:         "1.9.0",
+			Serial:             serial,
+			SSLPort:            "12309",
+			MachineName:        name,
+			MotorDriverVersion: "4.6",
+			BotType:            "replicator_5",
+			Port:               port,
+			FirmwareVersion:    &makerbot.FirmwareVersion{Major: 2, Minor: 6, Bugfix: 1, Build: 724},
+		},
+		speed:   speed,
+		nextID:  1,
+		clients: map[*simClient]bool{},
+		uploads: map[string]*simUpload{},
+		files:   map[string]int{},
+	}
+
+	sp.metadata.MachineName = name
+	sp.metadata.Toolheads.Extruder = []makerbot.ToolheadMetadata{
+		{Index: 0, ToolPresent: true, FilamentPresence: true, CurrentTemperature: 22},
+	}
+
+	return sp
+}
+
+// send queues `v`, followed by `raw`, to be written after everything that was
+// sent before it
+func (c *simClient) send(v interface{}, raw []byte) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.enqueue(append(data, raw...))
+	return nil
+}
+
+// enqueue queues `data` to be written after everything queued before it
+func (c *simClient) enqueue(data []byte) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.queue = append(c.queue, data)
+
+	if !c.flushing {
+		c.flushing = true
+		go c.flush()
+	}
+}
+
+// flush writes queued messages until the queue is empty. If a write fails, the
+// connection is closed and nothing else is written.
+func (c *simClient) flush() {
+	for {
+		c.Lock()
+		if len(c.queue) == 0 {
+			c.flushing = false
+			c.Unlock()
+			return
+		}
+
+		data := c.queue[0]
+		c.queue = c.queue[1:]
+		c.Unlock()
+
+		_, err := c.conn.Write(data)
+		if err != nil {
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+// notify sends the current state to every authorized client. It must be called
+// with sp locked, so the state is marshalled before anything can change it.
+func (sp *simPrinter) notify() {
+	data, err := json.Marshal(rpcNotification{
+		JSONRPC: "2.0",
+		Method:  "state_notification",
+		Params:  map[string]interface{}{"info": sp.metadata},
+	})
+	if err != nil {
+		return
+	}
+
+	for c := range sp.clients {
+		if c.authorized {
+			c.enqueue(data)
+		}
+	}
+}
+
+// startProcess replaces the current process. It must be called with sp locked.
+func (sp *simPrinter) startProcess(name, step string, methods []string) {
+	sp.metadata.CurrentProcess = &makerbot.PrinterProcess{
+		ID:          sp.nextID,
+		Name:        name,
+		Step:        step,
+		Cancellable: true,
+		Methods:     methods,
+	}
+	sp.nextID++
+	sp.suspended = false
+}
+
+// tick advances whatever the printer is doing by one second
+func (sp *simPrinter) tick() {
+	sp.Lock()
+	defer sp.Unlock()
+
+	tool := &sp.metadata.Toolheads.Extruder[0]
+	proc := sp.metadata.CurrentProcess
+
+	if proc == nil || proc.Complete || sp.suspended {
+		tool.TargetTemperature = 0
+	} else {
+		tool.TargetTemperature = 215
+	}
+
+	switch {
+	case tool.CurrentTemperature < tool.TargetTemperature:
+		tool.CurrentTemperature += 25
+		if tool.CurrentTemperature > tool.TargetTemperature {
+			tool.CurrentTemperature = tool.TargetTemperature
+		}
+	case tool.CurrentTemperature > 22 && tool.TargetTemperature == 0:
+		tool.CurrentTemperature -= 5
+		if tool.CurrentTemperature < 22 {
+			tool.CurrentTemperature = 22
+		}
+	}
+
+	if proc != nil && !proc.Complete && !sp.suspended {
+		proc.ElapsedTime++
+
+		if tool.CurrentTemperature < tool.TargetTemperature {
+			proc.Step = "heating"
+		} else {
+			proc.Step = map[string]string{
+				"PrintProcess":          "printing",
+				"LoadFilamentProcess":   "extrusion",
+				"UnloadFilamentProcess": "unloading_filament",
+			}[proc.Name]
+
+			proc.Progress += sp.speed
+			if proc.Progress >= 100 {
+				sp.finishProcess(proc)
+			}
+		}
+	}
+
+	sp.notify()
+}
+
+// finishProcess completes `proc`. It must be called with sp locked.
+func (sp *simPrinter) finishProcess(proc *makerbot.PrinterProcess) {
+	proc.Progress = 100
+
+	if proc.Name != "PrintProcess" {
+		sp.metadata.CurrentProcess = nil
+		return
+	}
+
+	proc.Step = "completed"
+	proc.Complete = true
+	proc.Cancellable = false
+	proc.Methods = []string{"acknowledge_completed"}
+}
+
+// frame renders a camera frame that shows the current progress
+func (sp *simPrinter) frame() []byte {
+	sp.Lock()
+	progress := 0
+	if sp.metadata.CurrentProcess != nil {
+		progress = sp.metadata.CurrentProcess.Progress
+	}
+	sp.Unlock()
+
+	img := image.NewRGBA(image.Rect(0, 0, simFrameWidth, simFrameHeight))
+	for y := 0; y < simFrameHeight; y++ {
+		for x := 0; x < simFrameWidth; x++ {
+			c := color.RGBA{R: 40, G: 40, B: uint8(60 + y/4), A: 255}
+			if y > simFrameHeight-20 && x < simFrameWidth*progress/100 {
+				c = color.RGBA{R: 230, G: 60, B: 40, A: 255}
+			}
+
+			img.Set(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, nil)
+
+	return buf.Bytes()
+}
+
+func (sp *simPrinter) call(c *simClient, method string, params json.RawMessage, r io.Reader) (interface{}, []byte, *rpcError) {
+	var p struct {
+		AccessToken string `json:"access_token"`
+		FileID      string `json:"file_id"`
+		FilePath    string `json:"filepath"`
+		Length      int    `json:"length"`
+		Method      string `json:"method"`
+		ToolIndex   int    `json:"tool_index"`
+	}
+	json.Unmarshal(params, &p)
+
+	if method == "put_raw" {
+		// put_raw is followed by `length` bytes of raw file data on the stream
+		data := make([]byte, p.Length)
+		_, err := io.ReadFull(r, data)
+		if err != nil {
+			return nil, nil, &rpcError{Code: -32700, Message: err.Error()}
+		}
+
+		sp.Lock()
+		defer sp.Unlock()
+
+		up, ok := sp.uploads[p.FileID]
+		if !ok {
+			return nil, nil, &rpcError{Code: 1, Message: "unknown file_id"}
+		}
+		up.data.Write(data)
+
+		return true, nil, nil
+	}
+
+	if method == "handshake" {
+		return sp.info, nil, nil
+	}
+
+	if method == "authorize" {
+		if p.AccessToken != simAccessToken {
+			return nil, nil, &rpcError{Code: 1, Message: "invalid access token"}
+		}
+
+		sp.Lock()
+		defer sp.Unlock()
+
+		c.authorized = true
+		sp.notify()
+
+		return true, nil, nil
+	}
+
+	if !c.authorized {
+		return nil, nil, &rpcError{Code: -32601, Message: "not authorized"}
+	}
+
+	if method == "request_camera_frame" {
+		// The frame follows the response on the stream, prefixed by its metadata
+		data := sp.frame()
+
+		var frame bytes.Buffer
+		binary.Write(&frame, binary.BigEndian, makerbot.CameraFrameMetadata{
+			FileSize: uint32(len(data)),
+			Width:    simFrameWidth,
+			Height:   simFrameHeight,
+			Format:   cameraFrameFormatJPEG,
+		})
+		frame.Write(data)
+
+		return true, frame.Bytes(), nil
+	}
+
+	sp.Lock()
+	defer sp.Unlock()
+
+	proc := sp.metadata.CurrentProcess
+	busy := proc != nil && !proc.Complete
+
+	switch method {
+	case "get_system_information":
+		return map[string]interface{}{"info": sp.info, "metadata": sp.metadata}, nil, nil
+
+	case "put_init":
+		sp.uploads[p.FileID] = &simUpload{name: p.FilePath, length: p.Length}
+		return true, nil, nil
+
+	case "put_term":
+		up, ok := sp.uploads[p.FileID]
+		if !ok {
+			return nil, nil, &rpcError{Code: 1, Message: "unknown file_id"}
+		}
+
+		delete(sp.uploads, p.FileID)
+		sp.files[up.name] = up.data.Len()
+
+		return true, nil, nil
+
+	case "print":
+		if busy {
+			return nil, nil, &rpcError{Code: 1, Message: "printer is busy"}
+		}
+
+		sp.startProcess("PrintProcess", "initializing", []string{"suspend"})
+		sp.metadata.CurrentProcess.FilePath = p.FilePath
+		sp.metadata.CurrentProcess.Filename = p.FilePath
+		sp.notify()
+
+		return true, nil, nil
+
+	case "load_filament", "unload_filament":
+		if busy {
+			return nil, nil, &rpcError{Code: 1, Message: "printer is busy"}
+		}
+
+		name := "LoadFilamentProcess"
+		if method == "unload_filament" {
+			name = "UnloadFilamentProcess"
+		}
+
+		sp.startProcess(name, "initializing", []string{"stop_filament"})
+		sp.notify()
+
+		return true, nil, nil
+
+	case "cancel":
+		if !busy || !proc.Cancellable {
+			return nil, nil, &rpcError{Code: 1, Message: "nothing to cancel"}
+		}
+
+		sp.metadata.CurrentProcess = nil
+		sp.notify()
+
+		return true, nil, nil
+
+	case "suspend", "resume", "process_method":
+		if method != "process_method" {
+			p.Method = method
+		}
+
+		return sp.processMethod(p.Method)
+	}
+
+	return nil, nil, &rpcError{Code: -32601, Message: "method not found"}
+}
+
+// processMethod runs one of the current process's methods. It must be called with
+// sp locked.
+func (sp *simPrinter) processMethod(method string) (interface{}, []byte, *rpcError) {
+	proc := sp.metadata.CurrentProcess
+	if proc == nil {
+		return nil, nil, &rpcError{Code: 1, Message: "no current process"}
+	}
+
+	allowed := false
+	for _, m := range proc.Methods {
+		allowed = allowed || m == method
+	}
+
+	if !allowed {
+		return nil, nil, &rpcError{Code: 1, Message: "method not available"}
+	}
+
+	switch method {
+	case "suspend":
+		sp.suspended = true
+		proc.Step = "suspended"
+		proc.Methods = []string{"resume"}
+	case "resume":
+		sp.suspended = false
+		proc.Methods = []string{"suspend"}
+	case "acknowledge_completed":
+		sp.metadata.CurrentProcess = nil
+	case "stop_filament":
+		sp.finishProcess(proc)
+	}
+
+	sp.notify()
+
+	return true, nil, nil
+}
+
+func (sp *simPrinter) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	c := &simClient{conn: conn}
+
+	sp.Lock()
+	sp.clients[c] = true
+	sp.Unlock()
+
+	defer func() {
+		sp.Lock()
+		delete(sp.clients, c)
+		sp.Unlock()
+	}()
+
+	var r io.Reader = bufio.NewReader(conn)
+	dec := json.NewDecoder(r)
+
+	for {
+		var req rpcRequest
+		err := dec.Decode(&req)
+		if err != nil {
+			return
+		}
+
+		// Raw data (put_raw) can follow a request, so the rest of the stream has to
+		// include whatever the decoder already buffered
+		r = io.MultiReader(dec.Buffered(), r)
+
+		result, raw, rerr := sp.call(c, req.Method, req.Params, r)
+
+		dec = json.NewDecoder(r)
+
+		if req.ID == nil {
+			continue
+		}
+
+		err = c.send(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rerr}, raw)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// serveAuth implements the printer's HTTP endpoint that hands out JSON-RPC access
+// tokens. The simulator accepts any Thingiverse credentials.
+func (sp *simPrinter) serveAuth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+
+	switch r.URL.Query().Get("response_type") {
+	case "code":
+		enc.Encode(map[string]string{"answer_code": "simulated-answer-code", "status": "ok"})
+	case "answer":
+		enc.Encode(map[string]string{"answer": "accepted", "code": "simulated-code"})
+	case "token":
+		enc.Encode(map[string]string{"access_token": simAccessToken, "status": "success"})
+	default:
+		http.Error(w, `{"status":"error"}`, http.StatusBadRequest)
+	}
+}
+
+// simulateCommand implements `makerbotd simulate`
+func simulateCommand(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	listen := fs.String("listen", ":9999", "the TCP address to serve JSON-RPC on")
+	httpListen := fs.String("http", ":80", "the TCP address to serve the authentication endpoint on")
+	name := fs.String("name", "Simulator", "the machine name of the simulated printer")
+	serial := fs.String("serial", "SIMULATOR0000000001", "the serial of the simulated printer")
+	speed := fs.Int("speed", 1, "how many percent of progress jobs make every second")
+	fs.Parse(args)
+
+	host, port, err := net.SplitHostPort(*listen)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if host == "" {
+		host = "127.0.0.1"
+	}
+
+	sp := newSimPrinter(*name, *serial, host, port, *speed)
+
+	sock, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	go func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/auth", sp.serveAuth)
+
+		log.Fatalln(http.ListenAndServe(*httpListen, mux))
+	}()
+
+	go func() {
+		for range time.Tick(time.Second) {
+			sp.tick()
+		}
+	}()
+
+	log.Printf("Simulated printer %s (%s) listening on %s, auth on %s", *name, *serial, *listen, *httpListen)
+	_, authPort, _ := net.SplitHostPort(*httpListen)
+	if authPort == defaultMakerbotAuthPort {
+		fmt.Printf("{\"ConnectionType\": \"local\", \"IP\": \"%s\", \"Port\": \"%s\"}\n", host, port)
+	} else {
+		fmt.Printf("{\"ConnectionType\": \"local\", \"IP\": \"%s\", \"Port\": \"%s\", \"AuthPort\": \"%s\"}\n", host, port, authPort)
+	}
+
+	for {
+		conn, err := sock.Accept()
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		go sp.serveConn(conn)
+	}
+}
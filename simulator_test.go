@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+// startSimulator serves a simulated printer on a local port. Its authentication
+// endpoint is served separately, at the returned URL.
+func startSimulator(t *testing.T) (sp *simPrinter, addr string, authURL string) {
+	sock, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sock.Close() })
+
+	host, port, _ := net.SplitHostPort(sock.Addr().String())
+	sp = newSimPrinter("Simulator", "SIM0001", host, port, 10)
+
+	go func() {
+		for {
+			conn, err := sock.Accept()
+			if err != nil {
+				return
+			}
+
+			go sp.serveConn(conn)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth", sp.serveAuth)
+	auth := httptest.NewServer(mux)
+	t.Cleanup(auth.Close)
+
+	return sp, sock.Addr().String(), auth.URL
+}
+
+// simTestClient speaks the printer's JSON-RPC protocol to the simulator
+type simTestClient struct {
+	t             *testing.T
+	conn          net.Conn
+	r             io.Reader
+	dec           *json.Decoder
+	nextID        int
+	notifications []makerbot.PrinterMetadata
+}
+
+func dialSimulator(t *testing.T, addr string) *simTestClient {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	r := bufio.NewReader(conn)
+	return &simTestClient{t: t, conn: conn, r: r, dec: json.NewDecoder(r), nextID: 1}
+}
+
+// read reads the next message, keeping any state notification
+func (c *simTestClient) read() (id int, result json.RawMessage, rerr *rpcError) {
+	var msg struct {
+		ID     *int            `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+
+	err := c.dec.Decode(&msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	if msg.ID == nil {
+		var params struct {
+			Info makerbot.PrinterMetadata `json:"info"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		c.notifications = append(c.notifications, params.Info)
+
+		return 0, nil, nil
+	}
+
+	return *msg.ID, msg.Result, msg.Error
+}
+
+// call makes a JSON-RPC call, followed by `raw` on the stream
+func (c *simTestClient) call(method string, params interface{}, raw []byte) (json.RawMessage, *rpcError) {
+	id := c.nextID
+	c.nextID++
+
+	req, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	c.conn.Write(append(req, raw...))
+
+	for {
+		got, result, rerr := c.read()
+		if got == id {
+			return result, rerr
+		}
+	}
+}
+
+// mustCall makes a JSON-RPC call and fails the test if it returns an error
+func (c *simTestClient) mustCall(method string, params interface{}) json.RawMessage {
+	result, rerr := c.call(method, params, nil)
+	if rerr != nil {
+		c.t.Fatalf("%s: %s", method, rerr.Message)
+	}
+
+	return result
+}
+
+// raw reads `n` bytes that follow the last response
+func (c *simTestClient) raw(n int) []byte {
+	c.r = io.MultiReader(c.dec.Buffered(), c.r)
+
+	data := make([]byte, n)
+	_, err := io.ReadFull(c.r, data)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	c.dec = json.NewDecoder(c.r)
+	return data
+}
+
+func (c *simTestClient) authorize(authURL string) {
+	res, err := http.Get(authURL + "/auth?response_type=token")
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	json.NewDecoder(res.Body).Decode(&token)
+
+	c.mustCall("authorize", map[string]string{"access_token": token.AccessToken})
+}
+
+func TestSimulatorAuthorization(t *testing.T) {
+	_, addr, authURL := startSimulator(t)
+	c := dialSimulator(t, addr)
+
+	var info makerbot.Printer
+	json.Unmarshal(c.mustCall("handshake", nil), &info)
+	if info.Serial != "SIM0001" || info.MachineName != "Simulator" {
+		t.Errorf("unexpected handshake %+v", info)
+	}
+
+	_, rerr := c.call("get_system_information", nil, nil)
+	if rerr == nil {
+		t.Error("expected an error before authorizing")
+	}
+
+	_, rerr = c.call("authorize", map[string]string{"access_token": "wrong"}, nil)
+	if rerr == nil {
+		t.Error("expected a wrong access token to be refused")
+	}
+
+	c.authorize(authURL)
+	c.mustCall("get_system_information", nil)
+}
+
+func TestSimulatorPrint(t *testing.T) {
+	sp, addr, authURL := startSimulator(t)
+	c := dialSimulator(t, addr)
+	c.authorize(authURL)
+
+	file := bytes.Repeat([]byte("print"), 1000)
+
+	c.mustCall("put_init", map[string]interface{}{"file_id": "1", "filepath": "/current_thing/part.makerbot", "length": len(file)})
+	for i := 0; i < len(file); i += 1024 {
+		end := i + 1024
+		if end > len(file) {
+			end = len(file)
+		}
+
+		_, rerr := c.call("put_raw", map[string]interface{}{"file_id": "1", "length": end - i}, file[i:end])
+		if rerr != nil {
+			t.Fatal(rerr.Message)
+		}
+	}
+	c.mustCall("put_term", map[string]interface{}{"file_id": "1"})
+
+	sp.Lock()
+	size := sp.files["/current_thing/part.makerbot"]
+	sp.Unlock()
+
+	if size != len(file) {
+		t.Fatalf("simulator received %d bytes, want %d", size, len(file))
+	}
+
+	c.mustCall("print", map[string]string{"filepath": "/current_thing/part.makerbot"})
+
+	_, rerr := c.call("print", map[string]string{"filepath": "/current_thing/part.makerbot"}, nil)
+	if rerr == nil {
+		t.Error("expected a second print to be refused while busy")
+	}
+
+	c.mustCall("suspend", nil)
+	c.mustCall("resume", nil)
+
+	// Heat up and print at 10% a second
+	for i := 0; i < 20; i++ {
+		sp.tick()
+	}
+
+	var state struct {
+		Metadata makerbot.PrinterMetadata `json:"metadata"`
+	}
+	json.Unmarshal(c.mustCall("get_system_information", nil), &state)
+
+	proc := state.Metadata.CurrentProcess
+	if proc == nil || !proc.Complete || proc.Step != "completed" {
+		t.Fatalf("print did not complete: %+v", proc)
+	}
+
+	c.mustCall("process_method", map[string]string{"method": "acknowledge_completed"})
+
+	json.Unmarshal(c.mustCall("get_system_information", nil), &state)
+	if state.Metadata.CurrentProcess != nil {
+		t.Errorf("process was not cleared after acknowledging it: %+v", state.Metadata.CurrentProcess)
+	}
+}
+
+func TestSimulatorCameraFrame(t *testing.T) {
+	_, addr, authURL := startSimulator(t)
+	c := dialSimulator(t, addr)
+	c.authorize(authURL)
+
+	c.mustCall("request_camera_frame", nil)
+
+	var meta makerbot.CameraFrameMetadata
+	err := binary.Read(bytes.NewReader(c.raw(binary.Size(meta))), binary.BigEndian, &meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if meta.Width != simFrameWidth || meta.Height != simFrameHeight || meta.Format != cameraFrameFormatJPEG {
+		t.Errorf("unexpected frame metadata %+v", meta)
+	}
+
+	data := c.raw(int(meta.FileSize))
+	if !bytes.HasPrefix(data, []byte{0xff, 0xd8}) {
+		t.Error("frame is not a JPEG")
+	}
+
+	// The connection must still be usable after the frame
+	c.mustCall("get_system_information", nil)
+}
+
+func TestSimulatorNotificationsInOrder(t *testing.T) {
+	sp, addr, authURL := startSimulator(t)
+	c := dialSimulator(t, addr)
+	c.authorize(authURL)
+
+	sp.Lock()
+	sp.speed = 1
+	sp.Unlock()
+
+	c.mustCall("load_filament", map[string]int{"tool_index": 0})
+
+	const ticks = 200
+	go func() {
+		for i := 0; i < ticks; i++ {
+			sp.tick()
+		}
+	}()
+
+	last := -1
+	for seen := 0; seen < ticks; {
+		c.read()
+		if len(c.notifications) == 0 {
+			continue
+		}
+
+		n := c.notifications[len(c.notifications)-1]
+		c.notifications = nil
+		seen++
+
+		if n.CurrentProcess == nil {
+			continue
+		}
+
+		elapsed := n.CurrentProcess.ElapsedTime
+		if elapsed < last {
+			t.Fatalf("notification for %ds arrived after one for %ds", elapsed, last)
+		}
+		last = elapsed
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+var errSpoolNotFound = errors.New("spool not found")
+
+type spool struct {
+	ID              string  `json:"id"`
+	Material        string  `json:"material"`
+	Color           string  `json:"color"`
+	Vendor          string  `json:"vendor"`
+	InitialLength   float64 `json:"initial_length"`    // InitialLength is in millimeters
+	RemainingLength float64 `json:"remaining_length"`  // RemainingLength is in millimeters
+	Printer         string  `json:"printer,omitempty"` // Printer is the ID of the printer the spool is loaded into
+	ToolIndex       *int    `json:"tool_index,omitempty"`
+}
+
+// spoolInventory keeps track of filament spools and which printer tool they're
+// loaded into. It is saved to disk after every change.
+type spoolInventory struct {
+	sync.Mutex
+	path   string
+	spools []*spool
+}
+
+func loadSpoolInventory(path string) (*spoolInventory, error) {
+	si := &spoolInventory{path: path, spools: []*spool{}}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return si, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &si.spools)
+	if err != nil {
+		return nil, err
+	}
+
+	return si, nil
+}
+
+// save writes the inventory to disk. It must be called with si locked.
+func (si *spoolInventory) save() error {
+	data, err := json.MarshalIndent(si.spools, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(si.path, data, 0600)
+}
+
+// find returns the spool with `id`. It must be called with si locked.
+func (si *spoolInventory) find(id string) (*spool, bool) {
+	for _, s := range si.spools {
+		if s.ID == id {
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+// loaded returns the spool in `printer`'s tool. It must be called with si locked.
+func (si *spoolInventory) loaded(printer string, toolIndex int) (*spool, bool) {
+	for _, s := range si.spools {
+		if s.Printer == printer && s.ToolIndex != nil && *s.ToolIndex == toolIndex {
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+func (si *spoolInventory) List() []spool {
+	si.Lock()
+	defer si.Unlock()
+
+	spools := []spool{}
+	for _, s := range si.spools {
+		spools = append(spools, *s)
+	}
+
+	return spools
+}
+
+func (si *spoolInventory) Get(id string) (spool, bool) {
+	si.Lock()
+	defer si.Unlock()
+
+	s, ok := si.find(id)
+	if !ok {
+		return spool{}, false
+	}
+
+	return *s, true
+}
+
+// LoadedIn lists the spools that are loaded into `printer`
+func (si *spoolInventory) LoadedIn(printer string) []spool {
+	si.Lock()
+	defer si.Unlock()
+
+	spools := []spool{}
+	for _, s := range si.spools {
+		if s.Printer == printer && s.ToolIndex != nil {
+			spools = append(spools, *s)
+		}
+	}
+
+	return spools
+}
+
+// Loaded returns the spool that is loaded into `printer`'s tool, if any
+func (si *spoolInventory) Loaded(printer string, toolIndex int) (spool, bool) {
+	si.Lock()
+	defer si.Unlock()
+
+	s, ok := si.loaded(printer, toolIndex)
+	if !ok {
+		return spool{}, false
+	}
+
+	return *s, true
+}
+
+// Add adds a new spool to the inventory. If RemainingLength is not set, the spool
+// is assumed to be full.
+func (si *spoolInventory) Add(s spool) (spool, error) {
+	si.Lock()
+	defer si.Unlock()
+
+	id := make([]byte, 8)
+	_, err := rand.Read(id)
+	if err != nil {
+		return spool{}, err
+	}
+
+	s.ID = hex.EncodeToString(id)
+	s.Printer = ""
+	s.ToolIndex = nil
+
+	if s.RemainingLength == 0 {
+		s.RemainingLength = s.InitialLength
+	}
+
+	si.spools = append(si.spools, &s)
+
+	return s, si.save()
+}
+
+func (si *spoolInventory) Remove(id string) error {
+	si.Lock()
+	defer si.Unlock()
+
+	for i, s := range si.spools {
+		if s.ID == id {
+			si.spools = append(si.spools[:i], si.spools[i+1:]...)
+			return si.save()
+		}
+	}
+
+	return errSpoolNotFound
+}
+
+// Assign records that spool `id` was loaded into `printer`'s tool. Whatever was
+// there before is unassigned.
+func (si *spoolInventory) Assign(id, printer string, toolIndex int) error {
+	si.Lock()
+	defer si.Unlock()
+
+	s, ok := si.find(id)
+	if !ok {
+		return errSpoolNotFound
+	}
+
+	if prev, ok := si.loaded(printer, toolIndex); ok {
+		prev.Printer = ""
+		prev.ToolIndex = nil
+	}
+
+	s.Printer = printer
+	s.ToolIndex = &toolIndex
+
+	return si.save()
+}
+
+// Unassign records that whatever was in `printer`'s tool was unloaded
+func (si *spoolInventory) Unassign(printer string, toolIndex int) error {
+	si.Lock()
+	defer si.Unlock()
+
+	s, ok := si.loaded(printer, toolIndex)
+	if !ok {
+		return nil
+	}
+
+	s.Printer = ""
+	s.ToolIndex = nil
+
+	return si.save()
+}
+
+// Deduct subtracts the filament used by each of `printer`'s tools from the spools
+// loaded into them
+func (si *spoolInventory) Deduct(printer string, usage []float64) error {
+	si.Lock()
+	defer si.Unlock()
+
+	for tool, mm := range usage {
+		s, ok := si.loaded(printer, tool)
+		if !ok {
+			continue
+		}
+
+		s.RemainingLength -= mm
+		if s.RemainingLength < 0 {
+			s.RemainingLength = 0
+		}
+	}
+
+	return si.save()
+}
+
+// Shortfalls returns, for each of `printer`'s tools, how much more filament
+// `usage` needs than the loaded spool has. Tools without a spool are skipped.
+func (si *spoolInventory) Shortfalls(printer string, usage []float64) map[int]float64 {
+	si.Lock()
+	defer si.Unlock()
+
+	short := map[int]float64{}
+	for tool, mm := range usage {
+		s, ok := si.loaded(printer, tool)
+		if ok && mm > s.RemainingLength {
+			short[tool] = mm - s.RemainingLength
+		}
+	}
+
+	return short
+}
+
+// setFilamentUsage remembers how much filament the print that was just sent needs
+func (pc *printerConnection) setFilamentUsage(usage []float64) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.filamentUsage = usage
+}
+
+func (pc *printerConnection) takeFilamentUsage() []float64 {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	usage := pc.filamentUsage
+	pc.filamentUsage = nil
+
+	return usage
+}
+
+// trackFilament deducts filament from the loaded spools when a print we sent
+// finishes. Cancelled or failed prints are charged for the fraction they got through.
+func (pc *printerConnection) trackFilament(old, new *makerbot.PrinterMetadata) {
+	if pc.context.Spools == nil || old == nil || old.CurrentProcess == nil {
+		return
+	}
+
+	op := old.CurrentProcess
+	if op.Complete || op.Name != "PrintProcess" {
+		return
+	}
+
+	var np *makerbot.PrinterProcess
+	if new != nil {
+		np = new.CurrentProcess
+	}
+
+	if np != nil && np.ID == op.ID && !np.Complete {
+		// Still printing
+		return
+	}
+
+	usage := pc.takeFilamentUsage()
+	if usage == nil {
+		return
+	}
+
+	fraction := float64(op.Progress) / 100
+	if np != nil && np.ID == op.ID && np.Step == "completed" && !np.Cancelled {
+		fraction = 1
+	}
+
+	used := make([]float64, len(usage))
+	for i, mm := range usage {
+		used[i] = mm * fraction
+	}
+
+	err := pc.context.Spools.Deduct(pc.ID(), used)
+	if err != nil {
+		pc.context.Debugf("printerConnection: could not update spools: %v\n", err)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxUploadSize = 1024 * 1024 * 1024
+
+	// uploadReadTimeout replaces the server's ReadTimeout for uploads, so large
+	// print files sent over slow connections aren't cut off after 5 minutes
+	uploadReadTimeout = time.Hour
+)
+
+var errUploadTooLarge = errors.New("print file is too large")
+
+func (ctx *mbContext) maxUploadSize() int64 {
+	if ctx.Config.MaxUploadSize > 0 {
+		return ctx.Config.MaxUploadSize
+	}
+
+	return defaultMaxUploadSize
+}
+
+// allowSlowUpload gives the rest of the request uploadReadTimeout to arrive
+func allowSlowUpload(w http.ResponseWriter) {
+	// Not every ResponseWriter supports deadlines, e.g. in tests, in which case
+	// there's no deadline to extend either
+	http.NewResponseController(w).SetReadDeadline(time.Now().Add(uploadReadTimeout))
+}
+
+func (a *APIv1) tooLarge(w http.ResponseWriter, r *http.Request) {
+	nf, _ := json.Marshal(apiError(fmt.Errorf("print files can be at most %d bytes", a.context.maxUploadSize())))
+	http.Error(w, string(nf), http.StatusRequestEntityTooLarge)
+}
+
+// spoolUpload copies an upload of unknown size into a temporary file. The caller
+// must hand the file to printerConnection.Print or discard it with discardUpload.
+func spoolUpload(body io.Reader, max int64) (*os.File, int64, error) {
+	file, err := ioutil.TempFile("", "makerbotd-upload-")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size, err := io.Copy(file, io.LimitReader(body, max+1))
+	if err == nil && size > max {
+		err = errUploadTooLarge
+	}
+
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, 0, err
+	}
+
+	return file, size, nil
+}
+
+// discardUpload closes and removes a spooled upload
+func discardUpload(file *os.File) {
+	file.Close()
+	os.Remove(file.Name())
+}
+
+// spoolPrint spools an uploaded print file to disk and sends it with sendPrint,
+// so it can be checked against the loaded spools before anything is sent to the
+// printer. If `size` isn't negative, the upload must be exactly that long.
+func (a *APIv1) spoolPrint(w http.ResponseWriter, r *http.Request, printer *printerConnection, name string, body io.Reader, size int64) {
+	file, got, err := spoolUpload(body, a.context.maxUploadSize())
+	if err == errUploadTooLarge {
+		a.tooLarge(w, r)
+		return
+	}
+
+	if err != nil {
+		a.badRequestError(w, r, err)
+		return
+	}
+
+	if size >= 0 && got != size {
+		discardUpload(file)
+		a.badRequestError(w, r, fmt.Errorf("print file is %d bytes, not %d", got, size))
+		return
+	}
+
+	a.sendPrint(w, r, printer, name, file, got)
+}
+
+// printRawBody handles an upload where the request body is the print file itself.
+// The file name comes from ?filename= or the Content-Disposition header.
+func (a *APIv1) printRawBody(w http.ResponseWriter, r *http.Request, printer *printerConnection) {
+	name := r.URL.Query().Get("filename")
+	if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); name == "" && err == nil {
+		name = params["filename"]
+	}
+
+	if name == "" {
+		a.badRequestError(w, r, errors.New("filename is required"))
+		return
+	}
+
+	if r.ContentLength < 0 {
+		nf, _ := json.Marshal(apiError(errors.New("Content-Length is required")))
+		http.Error(w, string(nf), http.StatusLengthRequired)
+		return
+	}
+
+	if r.ContentLength > a.context.maxUploadSize() {
+		a.tooLarge(w, r)
+		return
+	}
+
+	a.spoolPrint(w, r, printer, path.Base(name), r.Body, r.ContentLength)
+}
+
+// printMultipart handles a multipart upload without buffering it in memory. If a
+// "size" field comes before the "printfile" part, uploads that are too large are
+// refused before they are read.
+func (a *APIv1) printMultipart(w http.ResponseWriter, r *http.Request, printer *printerConnection) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		a.badRequest(w, r)
+		return
+	}
+
+	size := int64(-1)
+
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			// Ran out of parts without finding the file
+			a.badRequest(w, r)
+			return
+		}
+
+		switch part.FormName() {
+		case "size":
+			data, _ := ioutil.ReadAll(io.LimitReader(part, 32))
+			size, err = strconv.ParseInt(string(data), 10, 64)
+			if err != nil || size < 0 {
+				a.badRequestError(w, r, errors.New("size must be a number of bytes"))
+				return
+			}
+
+			if size > a.context.maxUploadSize() {
+				a.tooLarge(w, r)
+				return
+			}
+
+		case "printfile":
+			a.spoolPrint(w, r, printer, path.Base(part.FileName()), part, size)
+			return
+		}
+	}
+}
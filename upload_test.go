@@ -0,0 +1,268 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/tjhorner/makerbot-rpc"
+)
+
+// makerbotFile builds a .makerbot file that needs `mm` of filament
+func makerbotFile(t *testing.T, mm float64) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	meta, err := zw.Create("meta.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewEncoder(meta).Encode(map[string]interface{}{"duration_s": 600, "extrusion_distances_mm": []float64{mm}})
+
+	toolpath, _ := zw.Create("print.jsontoolpath")
+	toolpath.Write(bytes.Repeat([]byte("[]"), 1000))
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// loadSpool loads a spool with `remaining` mm of filament into the printer's first tool
+func loadSpool(t *testing.T, ctx *mbContext, printer string, remaining float64) {
+	s, err := ctx.Spools.Add(spool{Material: "PLA", InitialLength: 1000, RemainingLength: remaining})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctx.Spools.Assign(s.ID, printer, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// multipartUpload builds a multipart body with a "size" field followed by the file
+func multipartUpload(t *testing.T, field string, data []byte, extra map[string]string) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	for k, v := range extra {
+		mw.WriteField(k, v)
+	}
+
+	part, err := mw.CreateFormFile(field, "part.makerbot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	mw.Close()
+
+	return &body, mw.FormDataContentType()
+}
+
+func newUploadTestRouter(t *testing.T, conf *config) (*mbContext, *fakeBackend, http.Handler) {
+	conf.OctoPrintAPI = true
+	conf.OctoPrintPrinter = "fake"
+
+	ctx := newTestContext(t, conf)
+	_, fb := addFakePrinter(t, ctx, printerConfig{Name: "fake", ID: "1"})
+
+	router := newInstrumentedRouter()
+	(&APIv1{context: ctx}).Route(router)
+	(&APIOctoPrint{context: ctx}).Route(router)
+
+	return ctx, fb, router
+}
+
+func TestUploadRefusesLowFilament(t *testing.T) {
+	ctx, fb, router := newUploadTestRouter(t, &config{RefuseLowFilament: true})
+	loadSpool(t, ctx, "1", 100)
+
+	file := makerbotFile(t, 500)
+
+	multipartBody, multipartType := multipartUpload(t, "printfile", file, map[string]string{"size": strconv.Itoa(len(file))})
+	octoPrintBody, octoPrintType := multipartUpload(t, "file", file, map[string]string{"print": "true"})
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        *bytes.Buffer
+	}{
+		{"multipart with size", "/api/v1/printers/fake/prints", multipartType, multipartBody},
+		{"raw body", "/api/v1/printers/fake/prints?filename=part.makerbot", "application/octet-stream", bytes.NewBuffer(file)},
+		{"OctoPrint", "/api/files/local", octoPrintType, octoPrintBody},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", test.path, test.body)
+		req.Header.Set("Content-Type", test.contentType)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("%s: got %d %s, want 409", test.name, rec.Code, rec.Body)
+		}
+	}
+
+	if _, printed := fb.Printed("part.makerbot"); printed {
+		t.Error("a print that needs more filament than is loaded was sent to the printer")
+	}
+}
+
+func TestUploadWarnsAboutLowFilament(t *testing.T) {
+	ctx, fb, router := newUploadTestRouter(t, &config{})
+	loadSpool(t, ctx, "1", 100)
+
+	file := makerbotFile(t, 500)
+	body, contentType := multipartUpload(t, "printfile", file, map[string]string{"size": strconv.Itoa(len(file))})
+
+	req := httptest.NewRequest("POST", "/api/v1/printers/fake/prints", body)
+	req.Header.Set("Content-Type", contentType)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted || rec.Header().Get("Warning") == "" {
+		t.Fatalf("got %d with Warning %q, want 202 with a warning", rec.Code, rec.Header().Get("Warning"))
+	}
+
+	waitFor(t, func() bool {
+		printed, ok := fb.Printed("part.makerbot")
+		return ok && bytes.Equal(printed, file)
+	})
+}
+
+func TestUploadSizeMismatch(t *testing.T) {
+	_, _, router := newUploadTestRouter(t, &config{})
+
+	file := makerbotFile(t, 500)
+	body, contentType := multipartUpload(t, "printfile", file, map[string]string{"size": strconv.Itoa(len(file) + 1)})
+
+	req := httptest.NewRequest("POST", "/api/v1/printers/fake/prints", body)
+	req.Header.Set("Content-Type", contentType)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("got %d, want 400 for an upload that doesn't match its size", rec.Code)
+	}
+}
+
+func TestOctoPrintUploadSize(t *testing.T) {
+	_, fb, router := newUploadTestRouter(t, &config{MaxUploadSize: 100 * 1024 * 1024})
+
+	// Larger than the 50 MB OctoPrint uploads used to be limited to
+	file := bytes.Repeat([]byte("G1 X0\n"), 9*1024*1024)
+	body, contentType := multipartUpload(t, "file", file, map[string]string{"print": "true"})
+
+	req := httptest.NewRequest("POST", "/api/files/local", body)
+	req.Header.Set("Content-Type", contentType)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("got %d %s, want 201", rec.Code, rec.Body)
+	}
+
+	waitFor(t, func() bool {
+		printed, ok := fb.Printed("part.makerbot")
+		return ok && len(printed) == len(file)
+	})
+
+	_, _, router = newUploadTestRouter(t, &config{MaxUploadSize: 1000})
+	body, contentType = multipartUpload(t, "file", file[:2000], map[string]string{"print": "true"})
+
+	req = httptest.NewRequest("POST", "/api/files/local", body)
+	req.Header.Set("Content-Type", contentType)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("got %d, want 413 for an upload over MaxUploadSize", rec.Code)
+	}
+}
+
+func TestOctoPrintUploadRemembersPrint(t *testing.T) {
+	ctx, _, router := newUploadTestRouter(t, &config{})
+
+	body, contentType := multipartUpload(t, "file", makerbotFile(t, 500), map[string]string{"print": "true"})
+
+	req := httptest.NewRequest("POST", "/api/files/local", body)
+	req.Header.Set("Content-Type", contentType)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("got %d %s, want 201", rec.Code, rec.Body)
+	}
+
+	printer, _ := ctx.Printers.Find("fake")
+
+	printer.mu.Lock()
+	usage, duration := printer.filamentUsage, printer.expectedDuration
+	printer.mu.Unlock()
+
+	if len(usage) != 1 || usage[0] != 500 || duration != 600 {
+		t.Errorf("got filament usage %v and duration %v, want [500] and 600", usage, duration)
+	}
+}
+
+func TestOctoPrintAPIKey(t *testing.T) {
+	_, _, router := newUploadTestRouter(t, &config{OctoPrintAPIKey: "secret"})
+
+	for key, want := range map[string]int{"": http.StatusForbidden, "wrong": http.StatusForbidden, "secret": http.StatusOK} {
+		req := httptest.NewRequest("GET", "/api/version", nil)
+		req.Header.Set("X-Api-Key", key)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("got %d with key %q, want %d", rec.Code, key, want)
+		}
+	}
+}
+
+func TestOctoPrintJob(t *testing.T) {
+	_, fb, router := newUploadTestRouter(t, &config{})
+
+	job := func() octoPrintJob {
+		req := httptest.NewRequest("GET", "/api/job", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var job octoPrintJob
+		if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+			t.Fatal(err)
+		}
+
+		return job
+	}
+
+	if state := job().State; state != "Operational" {
+		t.Errorf("got state %q for an idle printer, want Operational", state)
+	}
+
+	fb.setState(func(m *makerbot.PrinterMetadata) {
+		m.CurrentProcess = &makerbot.PrinterProcess{Step: "printing", Filename: "part.makerbot", Progress: 40, ElapsedTime: 60}
+	})
+
+	waitFor(t, func() bool { return job().State == "Printing" })
+
+	got := job()
+	if got.Job.File.Name != "part.makerbot" || got.Progress.Completion == nil || *got.Progress.Completion != 40 {
+		t.Errorf("got job %+v, want part.makerbot at 40%%", got)
+	}
+}